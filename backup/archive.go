@@ -0,0 +1,206 @@
+package backup
+
+import (
+	"archive/tar"
+	"berquerant/install-via-git-go/errorx"
+	"berquerant/install-via-git-go/filepathx"
+	"berquerant/install-via-git-go/logx"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var ErrArchive = errors.New("Archive")
+
+func NewArchive(dir filepathx.DirPath, origin filepathx.Path) (*Archive, error) {
+	if err := dir.Ensure(); err != nil {
+		return nil, errorx.Errorf(err, "new backup")
+	}
+	return &Archive{
+		dir:    dir,
+		origin: origin,
+		path:   dir.Join(origin.Tail() + "." + string(FormatTarGz)).FilePath(),
+	}, nil
+}
+
+// Archive is a backup in a gzipped tar.
+//
+// The entries are relative to the parent of origin, Restore replaces origin with them.
+type Archive struct {
+	dir    filepathx.DirPath
+	origin filepathx.Path
+	path   filepathx.FilePath
+}
+
+var _ Maker = &Archive{}
+
+func (a *Archive) Dir() filepathx.DirPath   { return a.dir }
+func (a *Archive) Origin() filepathx.Path   { return a.origin }
+func (*Archive) Format() Format             { return FormatTarGz }
+func (a *Archive) Path() filepathx.FilePath { return a.path }
+
+func (a *Archive) Copy() error {
+	err := a.write()
+	logx.Debug("archive", logx.S("origin", a.origin.String()), logx.S("path", a.path.String()), logx.Err(err))
+	if err != nil {
+		return errorx.Errorf(errors.Join(ErrArchive, err), "backup %s", a.origin)
+	}
+	return nil
+}
+
+// Move archives origin and removes it.
+func (a *Archive) Move() error {
+	if err := a.Copy(); err != nil {
+		return err
+	}
+	if err := os.RemoveAll(a.origin.String()); err != nil {
+		return errorx.Errorf(errors.Join(ErrArchive, err), "remove %s", a.origin)
+	}
+	return nil
+}
+
+// Rename is Move because an archive cannot be renamed into.
+func (a *Archive) Rename() error {
+	return a.Move()
+}
+
+// Restore replaces origin with the archive, opt is ignored.
+func (a *Archive) Restore(_ ...ConfigOption) error {
+	if err := os.RemoveAll(a.origin.String()); err != nil {
+		return errorx.Errorf(errors.Join(ErrArchive, err), "remove %s", a.origin)
+	}
+	err := a.extract()
+	logx.Debug("extract", logx.S("origin", a.origin.String()), logx.S("path", a.path.String()), logx.Err(err))
+	if err != nil {
+		return errorx.Errorf(errors.Join(ErrArchive, err), "restore %s", a.origin)
+	}
+	return nil
+}
+
+func (a *Archive) Close() error {
+	return a.dir.Remove()
+}
+
+func (a *Archive) write() error {
+	f, err := os.Create(a.path.String())
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	zw := gzip.NewWriter(f)
+	tw := tar.NewWriter(zw)
+
+	base := a.origin.Parent().String()
+	if err := filepath.WalkDir(a.origin.String(), func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		var link string
+		if info.Mode()&fs.ModeSymlink != 0 {
+			if link, err = os.Readlink(path); err != nil {
+				return err
+			}
+		}
+		header, err := tar.FileInfoHeader(info, link)
+		if err != nil {
+			return err
+		}
+		name, err := filepath.Rel(base, path)
+		if err != nil {
+			return err
+		}
+		header.Name = filepath.ToSlash(name)
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		src, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer src.Close()
+		_, err = io.Copy(tw, src)
+		return err
+	}); err != nil {
+		return err
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		return err
+	}
+	return f.Sync()
+}
+
+func (a *Archive) extract() error {
+	f, err := os.Open(a.path.String())
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		return err
+	}
+	defer zr.Close()
+	tr := tar.NewReader(zr)
+
+	base := a.origin.Parent().String()
+	tail := a.origin.Tail()
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		name := filepath.FromSlash(header.Name)
+		if name != tail && !strings.HasPrefix(name, tail+string(filepath.Separator)) || !filepath.IsLocal(name) {
+			return fmt.Errorf("unexpected entry %s", header.Name)
+		}
+		path := filepath.Join(base, name)
+		mode := header.FileInfo().Mode()
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(path, mode.Perm()); err != nil {
+				return err
+			}
+		case tar.TypeSymlink:
+			if err := os.Symlink(header.Linkname, path); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := extractFile(path, mode.Perm(), tr); err != nil {
+				return err
+			}
+		default:
+			logx.Debug("skip entry", logx.S("name", header.Name))
+		}
+	}
+}
+
+func extractFile(path string, perm fs.FileMode, r io.Reader) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,178 @@
+package backup_test
+
+import (
+	"berquerant/install-via-git-go/backup"
+	"berquerant/install-via-git-go/filepathx"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestArchive(t *testing.T) {
+	base, err := filepathx.NewPath(t.TempDir())
+	if !assert.Nil(t, err) {
+		return
+	}
+	basePath := base.DirPath()
+
+	// srcdir/
+	//   file1 (0755)
+	//   link -> file1
+	//   dir1/
+	//     file2
+	//     dir2/
+	var (
+		srcDir = basePath.Join("srcdir").DirPath()
+		file1  = srcDir.Join("file1").FilePath()
+		link   = srcDir.Join("link")
+		dir1   = srcDir.Join("dir1").DirPath()
+		file2  = dir1.Join("file2").FilePath()
+		dir2   = dir1.Join("dir2").DirPath()
+	)
+	prepare := func(t *testing.T) {
+		assert.Nil(t, srcDir.Remove())
+		assert.Nil(t, dir2.Ensure())
+		assert.Nil(t, file1.Write("file1"))
+		assert.Nil(t, os.Chmod(file1.String(), 0755))
+		assert.Nil(t, os.Symlink("file1", link.String()))
+		assert.Nil(t, file2.Write("file2"))
+	}
+	check := func(t *testing.T) {
+		assert.True(t, dir2.Exist())
+		got, err := file1.Read()
+		assert.Nil(t, err)
+		assert.Equal(t, "file1", got)
+		stat, err := os.Stat(file1.String())
+		assert.Nil(t, err)
+		assert.Equal(t, os.FileMode(0755), stat.Mode().Perm())
+		target, err := os.Readlink(link.String())
+		assert.Nil(t, err)
+		assert.Equal(t, "file1", target)
+		got, err = file2.Read()
+		assert.Nil(t, err)
+		assert.Equal(t, "file2", got)
+		// added files are removed
+		assert.False(t, dir1.Join("added").FilePath().Exist())
+	}
+
+	for _, tc := range []struct {
+		title  string
+		change func(t *testing.T)
+	}{
+		{
+			title: "remove all",
+			change: func(t *testing.T) {
+				assert.Nil(t, srcDir.Remove())
+			},
+		},
+		{
+			title: "modify and add",
+			change: func(t *testing.T) {
+				assert.Nil(t, file2.Write("change"))
+				assert.Nil(t, dir1.Join("added").FilePath().Write("added"))
+				assert.Nil(t, dir2.Remove())
+			},
+		},
+	} {
+		t.Run(tc.title, func(t *testing.T) {
+			prepare(t)
+			b, err := backup.NewArchive(basePath.Join("archive").DirPath(), srcDir.Path)
+			if !assert.Nil(t, err) {
+				return
+			}
+			assert.Nil(t, b.Copy())
+			assert.True(t, b.Path().Exist())
+			tc.change(t)
+			assert.Nil(t, b.Restore())
+			check(t)
+			assert.Nil(t, b.Close())
+			assert.False(t, b.Dir().Exist())
+		})
+	}
+
+	t.Run("move", func(t *testing.T) {
+		prepare(t)
+		b, err := backup.NewArchive(basePath.Join("archive").DirPath(), srcDir.Path)
+		if !assert.Nil(t, err) {
+			return
+		}
+		defer b.Close()
+		assert.Nil(t, b.Move())
+		assert.False(t, srcDir.Exist())
+		assert.Nil(t, b.Restore())
+		check(t)
+	})
+
+	t.Run("file", func(t *testing.T) {
+		origin := basePath.Join("src.file")
+		assert.Nil(t, origin.FilePath().Write("zone"))
+		b, err := backup.NewArchive(basePath.Join("filearchive").DirPath(), origin)
+		if !assert.Nil(t, err) {
+			return
+		}
+		defer b.Close()
+		assert.Nil(t, b.Copy())
+		assert.Nil(t, origin.FilePath().Write("enoz"))
+		assert.Nil(t, b.Restore())
+		got, err := origin.FilePath().Read()
+		assert.Nil(t, err)
+		assert.Equal(t, "zone", got)
+	})
+}
+
+func TestStorage(t *testing.T) {
+	t.Run("ParseFormat", func(t *testing.T) {
+		for _, tc := range []struct {
+			input string
+			want  backup.Format
+			err   bool
+		}{
+			{input: "", want: backup.FormatCopy},
+			{input: "copy", want: backup.FormatCopy},
+			{input: "tar.gz", want: backup.FormatTarGz},
+			{input: "zip", err: true},
+		} {
+			t.Run(tc.input, func(t *testing.T) {
+				got, err := backup.ParseFormat(tc.input)
+				if tc.err {
+					assert.ErrorIs(t, err, backup.ErrUnknownFormat)
+					return
+				}
+				assert.Nil(t, err)
+				assert.Equal(t, tc.want, got)
+			})
+		}
+	})
+
+	t.Run("Create", func(t *testing.T) {
+		dir := t.TempDir() + "/backups"
+		origin := filepathx.Path(t.TempDir()).Join("origin")
+		for _, format := range backup.Formats {
+			t.Run(string(format), func(t *testing.T) {
+				b, err := backup.Storage{Format: format, Dir: dir}.Create(origin)
+				if !assert.Nil(t, err) {
+					return
+				}
+				defer b.Close()
+				assert.Equal(t, format, b.Format())
+				assert.Equal(t, origin, b.Origin())
+				assert.Equal(t, dir, b.Dir().Parent().String())
+				assert.True(t, strings.HasPrefix(b.Dir().Tail(), backup.TempDirPattern))
+			})
+		}
+	})
+
+	t.Run("Owner", func(t *testing.T) {
+		dir := t.TempDir()
+		origin := filepathx.Path(t.TempDir()).Join("origin")
+		b, err := backup.Storage{Format: backup.FormatCopy, Dir: dir, Owner: "/work"}.Create(origin)
+		if !assert.Nil(t, err) {
+			return
+		}
+		defer b.Close()
+		assert.True(t, strings.HasPrefix(b.Dir().Tail(), backup.OwnerPattern("/work")))
+		assert.False(t, strings.HasPrefix(b.Dir().Tail(), backup.OwnerPattern("/other")))
+	})
+}
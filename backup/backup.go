@@ -19,10 +19,18 @@ type Maker interface {
 	Move() error
 	// Rename renames origin to backup.
 	Rename() error
+	// Dir returns the directory of the backup.
+	Dir() filepathx.DirPath
+	// Origin returns the path backed up.
+	Origin() filepathx.Path
+	Format() Format
 }
 
+// TempDirPattern is the pattern of the temporary directories of IntoTempDir.
+const TempDirPattern = "install_via_git_backup"
+
 func IntoTempDir(origin filepathx.Path) (*Backup, error) {
-	dir, err := os.MkdirTemp("", "install_via_git_backup")
+	dir, err := os.MkdirTemp("", TempDirPattern)
 	if err != nil {
 		return nil, errorx.Errorf(err, "new backup")
 	}
@@ -41,12 +49,28 @@ func New(dir filepathx.DirPath, origin filepathx.Path) (*Backup, error) {
 	}, nil
 }
 
+var _ Maker = &Backup{}
+
 type Backup struct {
 	dir    filepathx.DirPath
 	origin filepathx.Path
 	path   filepathx.Path
 }
 
+// Dir returns the directory of the backup.
+func (b *Backup) Dir() filepathx.DirPath {
+	return b.dir
+}
+
+// Origin returns the path backed up.
+func (b *Backup) Origin() filepathx.Path {
+	return b.origin
+}
+
+func (*Backup) Format() Format {
+	return FormatCopy
+}
+
 func (b *Backup) Restore(opt ...ConfigOption) error {
 	config := NewConfigBuilder().Rename(false).Build()
 	config.Apply(opt...)
@@ -89,7 +113,7 @@ func (*Backup) copy(dst filepathx.Path, src filepathx.Path) error {
 	if isDir {
 		return src.DirPath().Copy(dst.DirPath())
 	}
-	return src.FilePath().Copy(dst.FilePath())
+	return src.FilePath().CopyAtomic(dst.FilePath())
 }
 
 func (b *Backup) Close() error {
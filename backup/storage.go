@@ -0,0 +1,93 @@
+package backup
+
+import (
+	"berquerant/install-via-git-go/errorx"
+	"berquerant/install-via-git-go/filepathx"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+)
+
+// Format is the format of the backup.
+type Format string
+
+const (
+	// FormatCopy copies origin file by file.
+	FormatCopy Format = "copy"
+	// FormatTarGz writes origin into a gzipped tar, smaller and faster for the big repos.
+	FormatTarGz Format = "tar.gz"
+)
+
+var (
+	Formats = []Format{FormatCopy, FormatTarGz}
+
+	ErrUnknownFormat = errors.New("UnknownFormat")
+)
+
+// ParseFormat returns the format of s, FormatCopy if empty.
+func ParseFormat(s string) (Format, error) {
+	if s == "" {
+		return FormatCopy, nil
+	}
+	if f := Format(s); slices.Contains(Formats, f) {
+		return f, nil
+	}
+	return "", errorx.Errorf(ErrUnknownFormat, "%s, want one of %v", s, Formats)
+}
+
+// Storage creates the backups.
+type Storage struct {
+	Format Format
+	// Dir is the directory to create the backups in, the system temp dir if empty.
+	Dir string
+	// Owner is the workDir the backups belong to, recorded in the names of the directories.
+	Owner string
+}
+
+// OwnerPattern returns the prefix of the names of the directories of the backups of owner.
+func OwnerPattern(owner string) string {
+	sum := sha256.Sum256([]byte(owner))
+	return TempDirPattern + "_" + hex.EncodeToString(sum[:8]) + "_"
+}
+
+func (s Storage) String() string {
+	dir := s.Dir
+	if dir == "" {
+		dir = os.TempDir()
+	}
+	return fmt.Sprintf("%s in %s", s.Format, dir)
+}
+
+// Create returns the Maker of origin in a new directory of the storage.
+func (s Storage) Create(origin filepathx.Path) (Maker, error) {
+	if s.Dir != "" {
+		if err := filepathx.Path(s.Dir).DirPath().Ensure(); err != nil {
+			return nil, errorx.Errorf(err, "new backup")
+		}
+	}
+	pattern := TempDirPattern
+	if s.Owner != "" {
+		pattern = OwnerPattern(s.Owner)
+	}
+	dir, err := os.MkdirTemp(s.Dir, pattern)
+	if err != nil {
+		return nil, errorx.Errorf(err, "new backup")
+	}
+	dirPath, _ := filepathx.NewPath(dir)
+	return Open(s.Format, dirPath.DirPath(), origin)
+}
+
+// Open returns the Maker of the backup of origin in dir.
+func Open(format Format, dir filepathx.DirPath, origin filepathx.Path) (Maker, error) {
+	switch format {
+	case FormatCopy, "":
+		return New(dir, origin)
+	case FormatTarGz:
+		return NewArchive(dir, origin)
+	default:
+		return nil, errorx.Errorf(ErrUnknownFormat, "%s", format)
+	}
+}
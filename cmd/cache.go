@@ -0,0 +1,45 @@
+package cmd
+
+import (
+	"berquerant/install-via-git-go/execx"
+	"berquerant/install-via-git-go/logx"
+	"berquerant/install-via-git-go/mirror"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	setMirrorDirFlag(cachePruneCmd)
+	cachePruneCmd.Flags().Duration("age", 0, "Remove mirrors not used for this duration, 0 means all")
+	cachePruneCmd.Flags().Bool("dry", false, "List mirrors to be removed, no side effects")
+	cacheCmd.AddCommand(cachePruneCmd)
+	rootCmd.AddCommand(cacheCmd)
+}
+
+var cacheCmd = &cobra.Command{
+	Use:   "cache",
+	Short: "Manage mirror cache",
+	Long:  `Manage the shared mirror cache used by run --mirror.`,
+}
+
+var cachePruneCmd = &cobra.Command{
+	Use:   "prune",
+	Short: "Remove mirrors",
+	Long:  `Remove mirrors from the mirror cache.`,
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		dir, err := getMirrorDir(cmd)
+		if err != nil {
+			return err
+		}
+		age, _ := cmd.Flags().GetDuration("age")
+		dry, _ := cmd.Flags().GetBool("dry")
+		logx.Info("prune mirrors", logx.S("dir", dir.String()), logx.S("age", age.String()), logx.B("dry", dry))
+
+		pruned, err := mirror.NewCache(dir, execx.NewEnv(), "git").Prune(cmd.Context(), time.Now(), age, dry)
+		for _, entry := range pruned {
+			cmd.Println(entry.Path.String())
+		}
+		return err
+	},
+}
@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"berquerant/install-via-git-go/git"
+	"berquerant/install-via-git-go/lock"
+	"berquerant/install-via-git-go/logx"
+	"berquerant/install-via-git-go/runner"
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+// changelog shows the changes between the locked commit and the new commit before install.
+type changelog struct {
+	gitCommand git.Command
+	// show writes the changes to out.
+	show bool
+	// confirm asks before install if in is a terminal.
+	confirm bool
+	in      *os.File
+	out     io.Writer
+	// changes are the commits of the last hook, one line per commit.
+	changes []string
+}
+
+var errInstallCanceled = errors.New("canceled by user")
+
+// hook returns the runner.Hook to run after the strategy set the next commit of pair.
+func (c *changelog) hook(pair *lock.Pair) runner.Hook {
+	return func(ctx context.Context) error {
+		if pair.Current == "" || pair.Next == "" || pair.Current == pair.Next {
+			return nil
+		}
+		log, err := c.gitCommand.Log(ctx, pair.Current, pair.Next)
+		if err != nil {
+			logx.Error("changelog", logx.S("from", pair.Current), logx.S("to", pair.Next), logx.Err(err))
+			return nil
+		}
+		c.changes = strings.Split(log, "\n")
+		if log == "" {
+			// e.g. Next is an ancestor of Current
+			c.changes = nil
+		}
+		if !c.show && !c.confirm {
+			return nil
+		}
+
+		stat, err := c.gitCommand.DiffStat(ctx, pair.Current, pair.Next)
+		if err != nil {
+			logx.Error("diffstat", logx.S("from", pair.Current), logx.S("to", pair.Next), logx.Err(err))
+		}
+		fmt.Fprintf(c.out, "Changes %s..%s\n%s\n\n%s\n", pair.Current, pair.Next, log, stat)
+		if !c.confirm {
+			return nil
+		}
+		if !isTerminal(c.in) {
+			logx.Info("skip confirm because stdin is not a terminal")
+			return nil
+		}
+		fmt.Fprint(c.out, "Proceed with install? [y/N]: ")
+		answer, _ := bufio.NewReader(c.in).ReadString('\n')
+		switch strings.ToLower(strings.TrimSpace(answer)) {
+		case "y", "yes":
+			return nil
+		default:
+			return errInstallCanceled
+		}
+	}
+}
+
+var isTerminal = func(f *os.File) bool {
+	return term.IsTerminal(int(f.Fd()))
+}
@@ -0,0 +1,164 @@
+package cmd
+
+import (
+	"berquerant/install-via-git-go/git"
+	"berquerant/install-via-git-go/lock"
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type mockChangelogCommand struct {
+	git.Command
+	log    map[string]string
+	called int
+}
+
+func (m *mockChangelogCommand) Log(_ context.Context, from, to string) (string, error) {
+	m.called++
+	return m.log[from+".."+to], nil
+}
+
+func (m *mockChangelogCommand) DiffStat(_ context.Context, from, to string) (string, error) {
+	return " f | 1 +\n 1 file changed, 1 insertion(+)", nil
+}
+
+func TestChangelogHook(t *testing.T) {
+	newInput := func(t *testing.T, content string) *os.File {
+		t.Helper()
+		p := filepath.Join(t.TempDir(), "stdin")
+		if !assert.Nil(t, os.WriteFile(p, []byte(content), 0600)) {
+			t.FailNow()
+		}
+		f, err := os.Open(p)
+		if !assert.Nil(t, err) {
+			t.FailNow()
+		}
+		t.Cleanup(func() { _ = f.Close() })
+		return f
+	}
+
+	for _, tc := range []struct {
+		title    string
+		pair     lock.Pair
+		show     bool
+		confirm  bool
+		terminal bool
+		input    string
+		want     []string
+		wantOut  []string
+		noOut    bool
+		noLog    bool
+		wantErr  error
+	}{
+		{
+			title: "no current",
+			pair:  lock.Pair{Next: "b"},
+			show:  true,
+			noOut: true,
+			noLog: true,
+		},
+		{
+			title: "no next",
+			pair:  lock.Pair{Current: "a"},
+			show:  true,
+			noOut: true,
+			noLog: true,
+		},
+		{
+			title: "equal",
+			pair:  lock.Pair{Current: "a", Next: "a"},
+			show:  true,
+			noOut: true,
+			noLog: true,
+		},
+		{
+			title: "record only",
+			pair:  lock.Pair{Current: "a", Next: "b"},
+			want:  []string{"b2 second", "b1 first"},
+			noOut: true,
+		},
+		{
+			title: "ancestor",
+			pair:  lock.Pair{Current: "b", Next: "a"},
+		},
+		{
+			title:   "show",
+			pair:    lock.Pair{Current: "a", Next: "b"},
+			show:    true,
+			want:    []string{"b2 second", "b1 first"},
+			wantOut: []string{"Changes a..b", "b2 second\nb1 first", "1 file changed"},
+		},
+		{
+			title:   "confirm not terminal",
+			pair:    lock.Pair{Current: "a", Next: "b"},
+			confirm: true,
+			input:   "n\n",
+			want:    []string{"b2 second", "b1 first"},
+			wantOut: []string{"Changes a..b"},
+		},
+		{
+			title:    "confirm yes",
+			pair:     lock.Pair{Current: "a", Next: "b"},
+			confirm:  true,
+			terminal: true,
+			input:    "y\n",
+			want:     []string{"b2 second", "b1 first"},
+			wantOut:  []string{"Changes a..b", "Proceed with install? [y/N]: "},
+		},
+		{
+			title:    "confirm no",
+			pair:     lock.Pair{Current: "a", Next: "b"},
+			confirm:  true,
+			terminal: true,
+			input:    "\n",
+			want:     []string{"b2 second", "b1 first"},
+			wantOut:  []string{"Proceed with install? [y/N]: "},
+			wantErr:  errInstallCanceled,
+		},
+	} {
+		t.Run(tc.title, func(t *testing.T) {
+			terminal := isTerminal
+			defer func() { isTerminal = terminal }()
+			isTerminal = func(*os.File) bool { return tc.terminal }
+
+			command := &mockChangelogCommand{
+				log: map[string]string{
+					"a..b": "b2 second\nb1 first",
+				},
+			}
+			var out bytes.Buffer
+			c := &changelog{
+				gitCommand: command,
+				show:       tc.show,
+				confirm:    tc.confirm,
+				in:         newInput(t, tc.input),
+				out:        &out,
+			}
+			pair := tc.pair
+			err := c.hook(&pair)(context.TODO())
+			if tc.wantErr != nil {
+				assert.ErrorIs(t, err, tc.wantErr)
+			} else {
+				assert.Nil(t, err)
+			}
+			assert.Equal(t, tc.want, c.changes)
+			if tc.noLog {
+				assert.Equal(t, 0, command.called)
+			}
+			if tc.noOut {
+				assert.Empty(t, out.String())
+			}
+			for _, x := range tc.wantOut {
+				assert.Contains(t, out.String(), x)
+			}
+			if !tc.terminal {
+				assert.NotContains(t, out.String(), "Proceed with install?")
+			}
+		})
+	}
+}
@@ -0,0 +1,131 @@
+package cmd
+
+import (
+	"berquerant/install-via-git-go/filepathx"
+	"berquerant/install-via-git-go/gc"
+	"berquerant/install-via-git-go/history"
+	"berquerant/install-via-git-go/journal"
+	"berquerant/install-via-git-go/logx"
+	"errors"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	setConfigFlag(gcCmd)
+	gcCmd.Flags().String("git", "git", "Git command")
+	gcCmd.Flags().StringP("workDir", "w", ".", "Working directory")
+	fail(gcCmd.MarkFlagDirname("workDir"))
+	setProcLockFlag(gcCmd)
+	gcCmd.Flags().Int("keep", 3, "Number of the latest installed worktrees to keep, including the linked one")
+	gcCmd.Flags().Duration("age", 24*time.Hour, "Remove backups not modified for this duration")
+	gcCmd.Flags().Bool("dry", false, "List what would be removed and its size, no side effects")
+	rootCmd.AddCommand(gcCmd)
+}
+
+var gcCmd = &cobra.Command{
+	Use:   "gc",
+	Short: "Remove unused backups and worktrees",
+	Long: `Remove unused backups and worktrees.
+
+Backups are the temporary directories left by the killed processes of this workDir,
+in the system temp dir and backup.dir of the config, except the ones in the journal to be recovered.
+Worktrees are the ones of worktree mode never installed successfully,
+and the ones beyond --keep latest installed according to the history.`,
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		common, err := prepareCommonResource(cmd)
+		if err != nil {
+			return err
+		}
+		defer common.release()
+		keep, _ := cmd.Flags().GetInt("keep")
+		age, _ := cmd.Flags().GetDuration("age")
+		dry, _ := cmd.Flags().GetBool("dry")
+		tempDir, err := filepathx.NewPath(os.TempDir())
+		if err != nil {
+			return err
+		}
+		logx.Info("gc", logx.S("tempDir", tempDir.String()), logx.S("age", age.String()), logx.B("dry", dry))
+
+		// the backups of the interrupted run are restored by the next run
+		j, interrupted, err := journal.FromWorkDir(common.workDir.DirPath()).Read()
+		if err != nil {
+			return err
+		}
+		var inUse []string
+		if interrupted {
+			for _, b := range j.Backups {
+				inUse = append(inUse, b.Dir)
+			}
+		}
+
+		targets, err := gc.Backups(tempDir.DirPath(), common.workDir.String(), time.Now(), age, inUse)
+		if err != nil {
+			return err
+		}
+		if dir := common.backupStorage().Dir; dir != "" && dir != tempDir.String() && filepathx.Path(dir).DirPath().Exist() {
+			logx.Info("gc", logx.S("backupDir", dir))
+			backups, err := gc.Backups(filepathx.Path(dir).DirPath(), common.workDir.String(), time.Now(), age, inUse)
+			if err != nil {
+				return err
+			}
+			targets = append(targets, backups...)
+		}
+		if common.stage != nil {
+			entries, err := history.FromWorkDir(common.workDir.DirPath()).Read()
+			if err != nil {
+				return err
+			}
+			worktrees, err := gc.Worktrees(common.stage, entries, keep)
+			if err != nil {
+				return err
+			}
+			targets = append(targets, worktrees...)
+		}
+
+		var (
+			total int64
+			errs  []error
+		)
+		w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "SIZE\tKIND\tPATH\tREASON")
+		for _, t := range targets {
+			size, err := t.Size()
+			if err != nil {
+				logx.Debug("size", logx.S("path", t.Path.String()), logx.Err(err))
+			}
+			total += size
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", formatSize(size), t.Kind, t.Path, t.Reason)
+			if dry {
+				continue
+			}
+			if err := t.Remove(cmd.Context()); err != nil {
+				logx.Error("gc", logx.Err(err))
+				errs = append(errs, err)
+			}
+		}
+		fmt.Fprintf(w, "%s\ttotal\t\t\n", formatSize(total))
+		if err := w.Flush(); err != nil {
+			return err
+		}
+		return errors.Join(errs...)
+	},
+}
+
+// formatSize returns the human readable size like 1.5MiB.
+func formatSize(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%dB", size)
+	}
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f%ciB", float64(size)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,83 @@
+package cmd
+
+import (
+	"berquerant/install-via-git-go/errorx"
+	"berquerant/install-via-git-go/history"
+	"encoding/json"
+	"fmt"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	historyCmd.Flags().StringP("workDir", "w", ".", "Working directory")
+	fail(historyCmd.MarkFlagDirname("workDir"))
+	historyCmd.Flags().String("strategy", "", "Show only the strategy, e.g. TupdateToLatestWithLock")
+	historyCmd.Flags().String("outcome", "", "Show only the outcome [success, rollback, noop, failure, canceled]")
+	historyCmd.Flags().Duration("since", 0, "Show only the installations within this duration, 0 means all")
+	historyCmd.Flags().IntP("limit", "n", 0, "Show only the latest n installations, 0 means all")
+	historyCmd.Flags().StringP("out", "o", "text", "Format [text, json]")
+	rootCmd.AddCommand(historyCmd)
+}
+
+var historyCmd = &cobra.Command{
+	Use:   "history",
+	Short: "Show install history",
+	Long:  `Show the installations recorded in workDir by run, oldest first.`,
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		workDir, err := getPath(cmd, "workDir")
+		if err != nil {
+			return errorx.Errorf(err, "invalid workDir")
+		}
+		entries, err := history.FromWorkDir(workDir.DirPath()).Read()
+		if err != nil {
+			return err
+		}
+
+		var filter history.Filter
+		filter.Strategy, _ = cmd.Flags().GetString("strategy")
+		outcome, _ := cmd.Flags().GetString("outcome")
+		filter.Outcome = history.Outcome(outcome)
+		if since, _ := cmd.Flags().GetDuration("since"); since > 0 {
+			filter.Since = time.Now().Add(-since)
+		}
+		filter.Limit, _ = cmd.Flags().GetInt("limit")
+		entries = filter.Apply(entries)
+
+		outputFormat, _ := cmd.Flags().GetString("out")
+		switch outputFormat {
+		case "json":
+			if entries == nil {
+				entries = []*history.Entry{}
+			}
+			v, _ := json.Marshal(entries)
+			cmd.Println(string(v))
+		case "text":
+			w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 4, 2, ' ', 0)
+			fmt.Fprintln(w, "TIME\tSTRATEGY\tOUTCOME\tFROM\tTO\tDURATION")
+			for _, e := range entries {
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n",
+					e.Time.Local().Format(time.RFC3339),
+					e.Strategy,
+					e.Outcome,
+					orDash(e.From),
+					orDash(e.To),
+					time.Duration(e.Duration).Round(time.Millisecond),
+				)
+			}
+			return w.Flush()
+		default:
+			return fmt.Errorf("unknown format %s", outputFormat)
+		}
+		return nil
+	},
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
@@ -0,0 +1,108 @@
+package cmd
+
+import (
+	"berquerant/install-via-git-go/config"
+	"berquerant/install-via-git-go/errorx"
+	"berquerant/install-via-git-go/execx"
+	"berquerant/install-via-git-go/filepathx"
+	"berquerant/install-via-git-go/git"
+	"berquerant/install-via-git-go/logx"
+	"berquerant/install-via-git-go/scaffold"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/goccy/go-yaml"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	initCmd.Flags().String("git", "git", "Git command")
+	initCmd.Flags().StringP("out", "o", "ivg.yml", "Output config file")
+	initCmd.Flags().Bool("force", false, "Overwrite the output config file")
+	initCmd.Flags().String("branch", "", "Target branch, default is the default branch of the repository")
+	rootCmd.AddCommand(initCmd)
+}
+
+var initCmd = &cobra.Command{
+	Use:   "init URI",
+	Short: "Generate config from repository",
+	Long: `Generate config from repository.
+
+Detect the default branch of the repository by ls-remote,
+then shallow clone it and detect the build systems to generate the install steps.
+Known build systems are Makefile, go.mod, Cargo.toml, CMakeLists.txt and package.json.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		out, _ := cmd.Flags().GetString("out")
+		if force, _ := cmd.Flags().GetBool("force"); !force {
+			if _, err := os.Stat(out); err == nil {
+				return fmt.Errorf("%s already exists, --force to overwrite", out)
+			}
+		}
+
+		_, uri, err := config.DetectSource(args[0])
+		if err != nil {
+			return err
+		}
+
+		tmpDir, err := os.MkdirTemp("", "ivg-init")
+		if err != nil {
+			return err
+		}
+		defer os.RemoveAll(tmpDir)
+		tmp, err := filepathx.NewPath(tmpDir)
+		if err != nil {
+			return err
+		}
+		gitCommandName, _ := cmd.Flags().GetString("git")
+		cli := git.NewCLI(tmp.Join("repo").DirPath(), execx.NewEnv(), gitCommandName)
+		gitCommand := git.NewCommand(cli)
+
+		branch, _ := cmd.Flags().GetString("branch")
+		if branch == "" {
+			branch, err = gitCommand.DefaultBranch(cmd.Context(), uri)
+			if err != nil {
+				if !errors.Is(err, git.ErrCLI) {
+					return errorx.Errorf(err, "detect default branch")
+				}
+				branch = "main"
+				logx.Info("cannot detect default branch, assume main", logx.Err(err))
+			}
+		}
+		logx.Info("branch", logx.S("uri", uri), logx.S("branch", branch))
+
+		if _, err := cli.ExecuteIn(
+			cmd.Context(), tmp.DirPath(),
+			"clone", "--depth", "1", "--single-branch", "--branch", branch, uri, cli.Dir().Tail(),
+		); err != nil {
+			return errorx.Errorf(err, "shallow clone")
+		}
+
+		systems := scaffold.Detect(cli.Dir())
+		names := make([]string, len(systems))
+		for i, x := range systems {
+			names[i] = x.Name
+		}
+		logx.Info("detected build systems", logx.SS("names", names))
+
+		v, err := yaml.Marshal(scaffold.Generate(uri, branch, systems))
+		if err != nil {
+			return err
+		}
+		var b strings.Builder
+		b.WriteString("# generated by install-via-git init\n")
+		if len(names) > 0 {
+			fmt.Fprintf(&b, "# detected build systems: %s\n", strings.Join(names, ", "))
+		} else {
+			b.WriteString("# no build systems detected, add install steps\n")
+		}
+		b.Write(v)
+		if err := os.WriteFile(out, []byte(b.String()), 0644); err != nil {
+			return err
+		}
+		logx.Info("generated", logx.S("path", out))
+		return nil
+	},
+}
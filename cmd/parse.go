@@ -1,8 +1,11 @@
 package cmd
 
 import (
+	"berquerant/install-via-git-go/config"
+	"berquerant/install-via-git-go/logx"
 	"encoding/json"
 	"fmt"
+	"os"
 
 	"github.com/goccy/go-yaml"
 	"github.com/spf13/cobra"
@@ -10,7 +13,9 @@ import (
 
 func init() {
 	setConfigFlag(parseCmd)
-	parseCmd.Flags().StringP("out", "o", "yaml", "Format [yaml, json]")
+	parseCmd.Flags().StringP("out", "o", "yaml", "Format [yaml, json, toml]")
+	parseCmd.Flags().Bool("showSecrets", false, "Show the values of the secret env keys")
+	parseCmd.Flags().Bool("origin", false, "Show the file each field comes from")
 	rootCmd.AddCommand(parseCmd)
 }
 
@@ -19,18 +24,39 @@ var parseCmd = &cobra.Command{
 	Short: "Parse config file",
 	Long:  `Parse config file.`,
 	RunE: func(cmd *cobra.Command, _ []string) error {
-		config, err := parseConfigFromFlag(cmd)
+		cfg, err := parseConfigFromFlag(cmd)
 		if err != nil {
 			return err
 		}
+		if showSecrets, _ := cmd.Flags().GetBool("showSecrets"); !showSecrets {
+			cfg = redactConfig(cfg)
+		}
 
+		showOrigin, _ := cmd.Flags().GetBool("origin")
+		var withOrigin any = cfg
+		if showOrigin {
+			withOrigin = map[string]any{
+				"config": cfg,
+				"origin": cfg.Origin,
+			}
+		}
 		outputFormat, _ := cmd.Flags().GetString("out")
 		switch outputFormat {
 		case "json":
-			v, _ := json.Marshal(config)
+			v, _ := json.Marshal(withOrigin)
 			cmd.Println(string(v))
+		case "toml":
+			v, err := config.MarshalTOML(withOrigin)
+			if err != nil {
+				return err
+			}
+			cmd.Print(string(v))
 		case "yaml", "yml":
-			v, _ := yaml.Marshal(config)
+			var opts []yaml.EncodeOption
+			if showOrigin {
+				opts = append(opts, yaml.WithComment(originComment(cfg.Origin)))
+			}
+			v, _ := yaml.MarshalWithOptions(cfg, opts...)
 			cmd.Println(string(v))
 		default:
 			return fmt.Errorf("unknown format %s", outputFormat)
@@ -38,3 +64,60 @@ var parseCmd = &cobra.Command{
 		return nil
 	},
 }
+
+// originComment makes the line comments of the origin files.
+func originComment(origin map[string]string) yaml.CommentMap {
+	m := yaml.CommentMap{}
+	for k, v := range origin {
+		m[k] = []*yaml.Comment{yaml.LineComment(" " + v)}
+	}
+	return m
+}
+
+// redactConfig returns a copy of cfg with masked secret env values, uri and steps.
+func redactConfig(cfg *config.Config) *config.Config {
+	// uri may be expanded from the process environment
+	logx.AddSecretEnv(os.Environ())
+	c := *cfg
+	c.URI = make(config.URIs, len(cfg.URI))
+	for i, x := range cfg.URI {
+		c.URI[i] = logx.Redact(x)
+	}
+	if cfg.URI == nil {
+		c.URI = nil
+	}
+	c.Steps = config.Steps{
+		Setup:     redactSteps(cfg.Steps.Setup),
+		Install:   redactSteps(cfg.Steps.Install),
+		Rollback:  redactSteps(cfg.Steps.Rollback),
+		Skip:      redactSteps(cfg.Steps.Skip),
+		Check:     redactSteps(cfg.Steps.Check),
+		Uninstall: redactSteps(cfg.Steps.Uninstall),
+	}
+	c.Env = make(map[string]string, len(cfg.Env))
+	for k, v := range cfg.Env {
+		if logx.IsSecretKey(k) {
+			c.Env[k] = logx.Redacted
+			continue
+		}
+		c.Env[k] = logx.Redact(v)
+	}
+	if len(c.Env) == 0 {
+		c.Env = cfg.Env
+	}
+	return &c
+}
+
+func redactSteps(steps []config.Step) []config.Step {
+	if steps == nil {
+		return nil
+	}
+	r := make([]config.Step, len(steps))
+	for i, x := range steps {
+		r[i] = config.Step{
+			Run:  logx.Redact(x.Run),
+			When: x.When,
+		}
+	}
+	return r
+}
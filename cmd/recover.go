@@ -0,0 +1,122 @@
+package cmd
+
+import (
+	"berquerant/install-via-git-go/backup"
+	"berquerant/install-via-git-go/errorx"
+	"berquerant/install-via-git-go/filepathx"
+	"berquerant/install-via-git-go/inspect"
+	"berquerant/install-via-git-go/journal"
+	"berquerant/install-via-git-go/lock"
+	"berquerant/install-via-git-go/logx"
+	"berquerant/install-via-git-go/strategy"
+	"context"
+	"errors"
+	"strconv"
+)
+
+// recoverInterrupted restores the repo and the lock to the state before the interrupted run, if the journal is left.
+// If dry, reports the interrupted run only.
+func (r *commonResource) recoverInterrupted(ctx context.Context, dry bool) error {
+	file := journal.FromWorkDir(r.workDir.DirPath())
+	j, ok, err := file.Read()
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return nil
+	}
+	logx.Error("detect interrupted run",
+		logx.S("pid", strconv.Itoa(j.PID)),
+		logx.S("started", j.Started.String()),
+		logx.S("phase", string(j.Phase)),
+		logx.S("next", j.Next),
+	)
+	if dry {
+		return nil
+	}
+
+	locked := lock.ParseContent(j.Lock)
+	lockFile := r.lockFile()
+	if j.Phase == journal.PhaseCommit && j.Next != "" {
+		if content, err := lockFile.Read(); err == nil && lock.ParseContent(content).Hash == j.Next {
+			logx.Info("recover: already committed", logx.S("hash", j.Next))
+			closeJournalBackups(j)
+			return file.Remove()
+		}
+	}
+
+	logx.Info("recover", logx.S("lock", locked.Hash))
+	var (
+		errs         []error
+		restoredLock bool
+		restoredRepo bool
+	)
+	for _, b := range j.Backups {
+		dir := filepathx.Path(b.Dir).DirPath()
+		if !dir.Exist() {
+			// e.g. removed by the system, recover from the lock of the journal instead
+			logx.Error("recover: backup lost", logx.S("origin", b.Origin), logx.S("dir", b.Dir))
+			continue
+		}
+		x, err := backup.Open(backup.Format(b.Format), dir, filepathx.Path(b.Origin))
+		if err == nil {
+			err = x.Restore()
+		}
+		if err != nil {
+			logx.Error("recover: restore backup", logx.S("origin", b.Origin), logx.Err(err))
+			errs = append(errs, err)
+			continue
+		}
+		_ = x.Close()
+		logx.Info("recover: restore backup", logx.S("origin", b.Origin))
+		switch b.Origin {
+		case lockFile.String():
+			restoredLock = true
+		case r.gitCommand.CLI().Dir().String():
+			restoredRepo = true
+		}
+	}
+	if !restoredLock {
+		if err := lockFile.WriteAtomic(j.Lock); err != nil {
+			errs = append(errs, errorx.Errorf(err, "recover lock"))
+		}
+	}
+	if locked.Hash != "" && inspect.RepoExistence(ctx, r.gitCommand) == strategy.REexist {
+		if err := r.gitCommand.ResetHard(ctx, locked.Hash); err != nil {
+			errs = append(errs, errorx.Errorf(err, "recover repo"))
+		}
+	} else if !restoredRepo {
+		logx.Info("recover: skip repo", logx.S("lock", locked.Hash))
+	}
+	if r.stage != nil {
+		// the worktrees are named by the full hashes
+		hash := locked.Hash
+		if resolved, err := r.gitCommand.ResolveCommit(ctx, hash); hash != "" && err == nil {
+			hash = resolved
+		}
+		if current, ok := r.stage.Current(); hash != "" && (!ok || current != hash) && r.stage.Path(hash).Exist() {
+			if err := r.stage.Swap(hash); err != nil {
+				errs = append(errs, err)
+			}
+		}
+		if j.Staged != "" && j.Staged != hash {
+			if err := r.stage.Remove(ctx, j.Staged); err != nil {
+				logx.Error("recover: remove worktree", logx.Err(err))
+			}
+		}
+	}
+
+	if len(errs) > 0 {
+		// keep the journal to retry
+		return errorx.Errorf(errors.Join(errs...), "recover")
+	}
+	return file.Remove()
+}
+
+func closeJournalBackups(j *journal.Journal) {
+	for _, b := range j.Backups {
+		if err := filepathx.Path(b.Dir).DirPath().Remove(); err != nil {
+			logx.Debug("remove backup", logx.S("dir", b.Dir), logx.Err(err))
+		}
+	}
+}
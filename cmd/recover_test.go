@@ -0,0 +1,88 @@
+package cmd
+
+import (
+	"berquerant/install-via-git-go/config"
+	"berquerant/install-via-git-go/execx"
+	"berquerant/install-via-git-go/filepathx"
+	"berquerant/install-via-git-go/git"
+	"berquerant/install-via-git-go/journal"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecoverInterruptedBackupLost(t *testing.T) {
+	workDir, err := filepathx.NewPath(t.TempDir())
+	if !assert.Nil(t, err) {
+		return
+	}
+	repoDir := workDir.Join("repo").DirPath()
+	if !assert.Nil(t, repoDir.Ensure()) {
+		return
+	}
+	env := execx.EnvFromSlice([]string{
+		"GIT_AUTHOR_NAME=ivg",
+		"GIT_AUTHOR_EMAIL=ivg@example.com",
+		"GIT_COMMITTER_NAME=ivg",
+		"GIT_COMMITTER_EMAIL=ivg@example.com",
+	})
+	res, err := execx.NewExecutorFromStrings([]string{
+		"git init -q -b main",
+		"echo 1 > f && git add f && git commit -q -m 1",
+		"git rev-parse HEAD",
+		"echo 2 > f && git commit -q -am 2",
+		"git rev-parse HEAD",
+	}, "bash").Execute(context.TODO(), execx.WithDir(repoDir), execx.WithEnv(env))
+	if !assert.Nil(t, err) {
+		return
+	}
+	hashes := strings.Fields(res.Stdout)
+	if !assert.Len(t, hashes, 2) {
+		return
+	}
+	locked, next := hashes[0], hashes[1]
+
+	r := &commonResource{
+		cfg: &config.Config{
+			LockFile: "lock",
+			LocalDir: "repo",
+		},
+		env:        execx.NewEnv(),
+		gitCommand: git.NewCommand(git.NewCLI(repoDir, execx.NewEnv(), "git")),
+		workDir:    workDir,
+	}
+	// interrupted while installing next, the backups were removed
+	if !assert.Nil(t, r.lockFile().Write(next)) {
+		return
+	}
+	file := journal.FromWorkDir(workDir.DirPath())
+	if !assert.Nil(t, file.Write(&journal.Journal{
+		PID:     1,
+		Started: time.Now(),
+		Phase:   journal.PhaseInstall,
+		Lock:    locked,
+		Backups: []journal.Backup{
+			{Dir: workDir.Join("lost_lock").String(), Origin: r.lockFile().String()},
+			{Dir: workDir.Join("lost_repo").String(), Origin: repoDir.String()},
+		},
+		Next: next,
+	})) {
+		return
+	}
+
+	if !assert.Nil(t, r.recoverInterrupted(context.TODO(), false)) {
+		return
+	}
+	gotLock, err := r.lockFile().Read()
+	assert.Nil(t, err)
+	assert.Equal(t, locked, strings.TrimSpace(gotLock))
+	head, err := r.gitCommand.GetCommitHash(context.TODO())
+	assert.Nil(t, err)
+	assert.Equal(t, locked, head)
+	_, ok, err := file.Read()
+	assert.Nil(t, err)
+	assert.False(t, ok, "journal should be removed")
+}
@@ -0,0 +1,208 @@
+package cmd
+
+import (
+	"berquerant/install-via-git-go/errorx"
+	"berquerant/install-via-git-go/execx"
+	"berquerant/install-via-git-go/filepathx"
+	"berquerant/install-via-git-go/git"
+	"berquerant/install-via-git-go/gitlock"
+	"berquerant/install-via-git-go/history"
+	"berquerant/install-via-git-go/inspect"
+	"berquerant/install-via-git-go/lock"
+	"berquerant/install-via-git-go/logx"
+	"berquerant/install-via-git-go/runner"
+	"berquerant/install-via-git-go/strategy"
+	"berquerant/install-via-git-go/worktree"
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	setConfigFlag(rollbackCmd)
+	setShellFlag(rollbackCmd)
+	rollbackCmd.Flags().String("git", "git", "Git command")
+	rollbackCmd.Flags().StringP("workDir", "w", ".", "Working directory")
+	fail(rollbackCmd.MarkFlagDirname("workDir"))
+	setProcLockFlag(rollbackCmd)
+	rollbackCmd.Flags().String("to", "", "Commit hash to return to")
+	rollbackCmd.Flags().IntP("steps", "n", 1, "Return to the commit installed n installations before")
+	rollbackCmd.Flags().Bool("dry", false, "Determine the commit to return to, no side effects")
+	rollbackCmd.MarkFlagsMutuallyExclusive("to", "steps")
+	rootCmd.AddCommand(rollbackCmd)
+}
+
+var rollbackCmd = &cobra.Command{
+	Use:   "rollback",
+	Short: "Return to a previous installed commit",
+	Long: `Return to a previous installed commit.
+
+Checkout the commit of --to, or the commit installed --steps installations before according to the history,
+then execute install and update the lock.
+If worktree is enabled and the worktree of the commit exists, swap the symlink without install.`,
+	RunE: rollback,
+}
+
+func rollback(cmd *cobra.Command, _ []string) error {
+	common, err := prepareCommonResource(cmd)
+	if err != nil {
+		return err
+	}
+	defer common.release()
+	if !common.matchWhen() {
+		return nil
+	}
+	if inspect.RepoExistence(cmd.Context(), common.gitCommand) == strategy.REnone {
+		return fmt.Errorf("no local repo %s", common.gitCommand.CLI().Dir())
+	}
+
+	lockFile := common.lockFile()
+	content, err := lockFile.Read()
+	if err != nil {
+		return errorx.Errorf(err, "read lock")
+	}
+	lockContent := lock.ParseContent(content)
+	to, _ := cmd.Flags().GetString("to")
+	steps, _ := cmd.Flags().GetInt("steps")
+	target, err := rollbackTarget(common.workDir.DirPath(), lockContent, to, steps)
+	if err != nil {
+		return err
+	}
+	logx.Info("rollback",
+		logx.S("current", lockContent.Hash),
+		logx.S("target", target),
+		logx.S("lock", lockFile.String()),
+	)
+
+	if dry, _ := cmd.Flags().GetBool("dry"); dry {
+		return nil
+	}
+
+	shell := getShell(cmd, common.cfg)
+	logx.Info("start rollback!", logx.SS("shell", shell))
+	return (&rollbackRunner{
+		Argument: &runner.Argument{
+			Config:       common.cfg,
+			Env:          common.env,
+			Shell:        shell,
+			LocalRepoDir: common.localDir(),
+		},
+		workDir:     common.workDir.DirPath(),
+		lockFile:    lockFile,
+		lockContent: lockContent,
+		gitCommand:  common.gitCommand,
+		target:      target,
+		stage:       common.stage,
+	}).run(cmd.Context())
+}
+
+// rollbackTarget returns the commit to return to.
+func rollbackTarget(workDir filepathx.DirPath, lockContent lock.Content, to string, steps int) (string, error) {
+	if to != "" {
+		return to, nil
+	}
+	entries, err := history.FromWorkDir(workDir).Read()
+	if err != nil {
+		return "", err
+	}
+	if target, ok := history.Previous(entries, lockContent.Hash, steps); ok {
+		return target, nil
+	}
+	return "", fmt.Errorf("no commit installed %d installations before %s", steps, lockContent.Hash)
+}
+
+type rollbackRunner struct {
+	*runner.Argument
+	workDir     filepathx.DirPath
+	lockFile    filepathx.FilePath
+	lockContent lock.Content
+	gitCommand  git.Command
+	target      string
+	// stage is not nil if worktree is enabled.
+	stage *worktree.Stage
+	// staged is the hash of the worktree added by this rollback.
+	staged string
+}
+
+func (r *rollbackRunner) run(ctx context.Context) error {
+	start := time.Now()
+	keeper := gitlock.NewGitKeeper(lock.NewFileKeeper(r.lockFile), r.gitCommand)
+	pair := keeper.Locker().Pair()
+	pair.Remote = r.lockContent.Remote
+	pair.Branch = r.lockContent.Branch
+
+	localDir := r.LocalRepoDir
+	err := r.install(ctx, pair)
+	if err == nil && r.stage != nil {
+		err = r.stage.Swap(pair.Next)
+	}
+	if err == nil {
+		if err := keeper.Commit(); err != nil {
+			return errorx.Errorf(err, "commit")
+		}
+		r.record(start, pair, history.OutcomeSuccess, nil)
+		return nil
+	}
+
+	logx.Error("rollback to target", logx.Err(err))
+	r.LocalRepoDir = localDir
+	_ = runner.NewRollback(r.Argument, keeper, false).Run(ctx)
+	if r.staged != "" {
+		if err := r.stage.Remove(ctx, r.staged); err != nil {
+			logx.Error("remove worktree", logx.Err(err))
+		}
+	}
+	r.record(start, pair, history.OutcomeRollback, err)
+	return err
+}
+
+func (r *rollbackRunner) install(ctx context.Context, pair *lock.Pair) error {
+	if err := r.gitCommand.Checkout(ctx, r.target); err != nil {
+		logx.Info("fetch because checkout failed", logx.S("target", r.target), logx.Err(err))
+		if err := r.gitCommand.Fetch(ctx); err != nil {
+			return errorx.Errorf(err, "fetch")
+		}
+		if err := r.gitCommand.Checkout(ctx, r.target); err != nil {
+			return errorx.Errorf(err, "checkout %s", r.target)
+		}
+	}
+	next, err := r.gitCommand.GetCommitHash(ctx)
+	if err != nil {
+		return errorx.Errorf(err, "get commit hash")
+	}
+	pair.Next = next
+
+	if r.stage != nil {
+		if r.stage.Path(next).Exist() {
+			logx.Info("skip install because the worktree exists", logx.S("path", r.stage.Path(next).String()))
+			return nil
+		}
+		dir, err := r.stage.Add(ctx, next)
+		if err != nil {
+			return err
+		}
+		r.staged = next
+		r.LocalRepoDir = dir
+	}
+
+	logx.Info("install")
+	if _, err := r.Executor(r.Config.Steps.Install).
+		Execute(ctx, execx.WithDir(r.LocalRepoDir), execx.WithEnv(r.Env)); err != nil {
+		return errorx.Errorf(err, "run install")
+	}
+	return nil
+}
+
+func (r *rollbackRunner) record(start time.Time, pair *lock.Pair, outcome history.Outcome, err error) {
+	recordHistory(r.workDir, &history.Entry{
+		Time:     start,
+		URI:      r.Config.URI.Primary(),
+		Strategy: history.StrategyRollback,
+		From:     pair.Current,
+		To:       pair.Next,
+		Outcome:  outcome,
+		Duration: history.Duration(time.Since(start)),
+	}, err)
+}
@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"berquerant/install-via-git-go/backup"
 	"berquerant/install-via-git-go/config"
 	"berquerant/install-via-git-go/errorx"
 	"berquerant/install-via-git-go/execx"
@@ -8,9 +9,17 @@ import (
 	"berquerant/install-via-git-go/filepathx"
 	"berquerant/install-via-git-go/git"
 	"berquerant/install-via-git-go/logx"
+	"berquerant/install-via-git-go/mirror"
+	"berquerant/install-via-git-go/proclock"
+	"berquerant/install-via-git-go/remoteconfig"
+	"berquerant/install-via-git-go/worktree"
 	"context"
+	"errors"
+	"fmt"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"strings"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
@@ -60,32 +69,130 @@ func displayFlags(flags *pflag.FlagSet) {
 }
 
 func setConfigFlag(cmd *cobra.Command) {
-	cmd.Flags().StringP("config", "c", "ivg.yml", "Configuration file, - to read from stdin")
-	fail(cmd.MarkFlagFilename("config", "yml", "yaml"))
+	cmd.Flags().StringP("config", "c", "ivg.yml", "Configuration file, - to read from stdin, git+REPO//PATH@REF to read from a git repository")
+	fail(cmd.MarkFlagFilename("config", "yml", "yaml", "json", "toml"))
+	cmd.Flags().Bool("strict", false, "Fail on undefined ${NAME} variables in config")
+	cmd.Flags().String("profile", "", "Profile to merge over the config, default is $IVG_PROFILE if the config has it")
+	cmd.Flags().String("format", "", fmt.Sprintf("Configuration format %v, detected from the extension or the content if empty", config.Formats))
+	cmd.Flags().String("configTokenEnv", "IVG_CONFIG_TOKEN", "Environment variable of the access token for the git+REPO config")
+	cmd.Flags().String("configSSHKey", "", "Private key for the git+REPO config")
 }
 
 func parseConfigFromFlag(cmd *cobra.Command) (*config.Config, error) {
 	cfg, _ := cmd.Flags().GetString("config")
-	return parseConfigFromOption(cfg)
+	parseOpt, err := parseOptionFromFlag(cmd)
+	if err != nil {
+		return nil, err
+	}
+	return parseConfigFromOption(cmd.Context(), cfg, configAuthFromFlag(cmd), parseOpt...)
+}
+
+// configAuthFromFlag returns the credentials for the git+REPO config, nil if not given.
+// The auth of the config is not available because it is in the repository.
+func configAuthFromFlag(cmd *cobra.Command) *git.Auth {
+	tokenEnv, _ := cmd.Flags().GetString("configTokenEnv")
+	sshKey, _ := cmd.Flags().GetString("configSSHKey")
+	auth := &git.Auth{
+		Username: "x-access-token",
+		SSHKey:   sshKey,
+	}
+	if tokenEnv != "" {
+		auth.Token = os.Getenv(tokenEnv)
+	}
+	if auth.Token == "" && auth.SSHKey == "" {
+		return nil
+	}
+	logx.AddSecrets(auth.Secrets()...)
+	return auth
+}
+
+func parseOptionFromFlag(cmd *cobra.Command) ([]config.ParseConfigOption, error) {
+	strict, _ := cmd.Flags().GetBool("strict")
+	format, _ := cmd.Flags().GetString("format")
+	profile, _ := cmd.Flags().GetString("profile")
+	vars := map[string]string{}
+	if cmd.Flags().Lookup("workDir") != nil {
+		workDir, err := getPath(cmd, "workDir")
+		if err != nil {
+			return nil, errorx.Errorf(err, "invalid workDir")
+		}
+		vars["IVG_WORKD"] = workDir.String()
+	}
+	return []config.ParseConfigOption{
+		config.WithStrict(strict),
+		config.WithVars(vars),
+		config.WithFormat(format),
+		config.WithProfile(profile),
+		config.WithDefaultProfile(os.Getenv("IVG_PROFILE")),
+	}, nil
+}
+
+// resolveConfigFile returns the config file of opt and the function to release it.
+// If opt is git+REPO//PATH@REF, checkouts the repository into the cache with auth and returns the file in it,
+// the checkout is kept until released.
+func resolveConfigFile(ctx context.Context, opt string, auth *git.Auth) (string, func(), error) {
+	if !remoteconfig.IsLocation(opt) {
+		return opt, func() {}, nil
+	}
+	loc, err := remoteconfig.ParseLocation(opt)
+	if err != nil {
+		return "", nil, err
+	}
+	dir, err := remoteconfig.DefaultDir()
+	if err != nil {
+		return "", nil, err
+	}
+	var gitOpts []git.ConfigOption
+	if auth != nil {
+		a := *auth
+		a.URL = loc.Repo
+		gitOpts = append(gitOpts, git.WithAuth(&a))
+	}
+	file, lock, err := remoteconfig.NewCache(dir, execx.NewEnv(), "git", gitOpts...).Checkout(ctx, loc)
+	if err != nil {
+		return "", nil, errorx.Errorf(err, "load remote config %s", opt)
+	}
+	return file.String(), func() {
+		if err := lock.Release(); err != nil {
+			logx.Error("release remote config", logx.Err(err))
+		}
+	}, nil
 }
 
-func parseConfigFromOption(opt string) (*config.Config, error) {
+func parseConfigFromOption(ctx context.Context, opt string, auth *git.Auth, parseOpt ...config.ParseConfigOption) (*config.Config, error) {
 	logx.Info("config", logx.S("value", opt))
-	if opt == "-" {
-		return parseConfigFromStdin()
+	cfg, err := func() (*config.Config, error) {
+		if opt == "-" {
+			return parseConfigFromStdin(parseOpt...)
+		}
+		file, release, err := resolveConfigFile(ctx, opt, auth)
+		if err != nil {
+			return nil, err
+		}
+		defer release()
+		return parseConfigFile(file, parseOpt...)
+	}()
+	if err != nil {
+		return nil, err
 	}
-	return parseConfigFile(opt)
+	logx.AddSecretKeys(cfg.Secrets...)
+	for k, v := range cfg.Env {
+		if logx.IsSecretKey(k) {
+			logx.AddSecrets(v)
+		}
+	}
+	return cfg, nil
 }
 
-func parseConfigFromStdin() (*config.Config, error) {
-	cfg, err := config.Parse(os.Stdin)
+func parseConfigFromStdin(parseOpt ...config.ParseConfigOption) (*config.Config, error) {
+	cfg, err := config.Parse(os.Stdin, parseOpt...)
 	if err != nil {
 		return nil, errorx.Errorf(err, "load config from stdin")
 	}
 	return cfg, nil
 }
 
-func parseConfigFile(cfgFile string) (*config.Config, error) {
+func parseConfigFile(cfgFile string, parseOpt ...config.ParseConfigOption) (*config.Config, error) {
 	logx.Debug("parse config", logx.S("path", cfgFile))
 
 	cfg, err := func() (*config.Config, error) {
@@ -98,7 +205,7 @@ func parseConfigFile(cfgFile string) (*config.Config, error) {
 			return nil, err
 		}
 		defer f.Close()
-		return config.Parse(f)
+		return config.Parse(f, append(parseOpt, config.WithPath(cfgFile))...)
 	}()
 	if err != nil {
 		return nil, errorx.Errorf(err, "load config file %s", cfgFile)
@@ -135,17 +242,155 @@ func getShell(cmd *cobra.Command, cfg *config.Config) []string {
 	return []string{"bash"}
 }
 
+func setMirrorDirFlag(cmd *cobra.Command) {
+	cmd.Flags().String("mirrorDir", "", "Mirror cache directory, default is ~/.cache/install-via-git/mirrors")
+	fail(cmd.MarkFlagDirname("mirrorDir"))
+}
+
+func getMirrorDir(cmd *cobra.Command) (filepathx.DirPath, error) {
+	v, _ := cmd.Flags().GetString("mirrorDir")
+	if v == "" {
+		return mirror.DefaultDir()
+	}
+	p, err := filepathx.NewPath(v)
+	if err != nil {
+		return filepathx.DirPath{}, errorx.Errorf(err, "invalid mirrorDir")
+	}
+	return p.DirPath(), nil
+}
+
+func setProcLockFlag(cmd *cobra.Command) {
+	cmd.Flags().Bool("wait", false, "Wait for the other process running on the same workDir to finish")
+	cmd.Flags().Duration("timeout", 0, "Give up waiting after this duration with --wait, 0 means no limit")
+}
+
+// acquireProcLock locks workDir to prevent the simultaneous runs.
+// Returns nil without creating workDir and the lock if --dry, the dry run has no side effects.
+func acquireProcLock(cmd *cobra.Command, workDir filepathx.DirPath) (*proclock.Lock, error) {
+	if dry, _ := cmd.Flags().GetBool("dry"); dry {
+		logx.Debug("skip proclock", logx.B("dry", dry))
+		return nil, nil
+	}
+	wait, _ := cmd.Flags().GetBool("wait")
+	timeout, _ := cmd.Flags().GetDuration("timeout")
+	if err := workDir.Ensure(); err != nil {
+		return nil, errorx.Errorf(err, "ensure workDir")
+	}
+	l, err := proclock.Acquire(cmd.Context(), proclock.FromWorkDir(workDir), wait, timeout)
+	if err != nil {
+		if !wait && errors.Is(err, proclock.ErrLocked) {
+			return nil, errorx.Errorf(err, "another process is running, retry with --wait")
+		}
+		return nil, err
+	}
+	return l, nil
+}
+
+// newAuth reads the credentials, returns nil if no auth configured.
+// If not required, the missing token is not an error because the command never touches the network.
+func newAuth(cfg *config.Config, env execx.Env, required bool) (*git.Auth, error) {
+	if cfg.Auth == nil {
+		return nil, nil
+	}
+	auth := &git.Auth{
+		URL:        cfg.URI.Primary(),
+		Username:   cfg.Auth.Username,
+		SSHKey:     cfg.Auth.SSHKey,
+		KnownHosts: cfg.Auth.KnownHosts,
+	}
+	if auth.Username == "" {
+		auth.Username = "x-access-token"
+	}
+	switch {
+	case cfg.Auth.TokenEnv != "":
+		token, ok := env.Get(cfg.Auth.TokenEnv)
+		if !ok {
+			token = os.Getenv(cfg.Auth.TokenEnv)
+		}
+		if token == "" && required {
+			return nil, errorx.Errorf(git.ErrNoToken, "empty token in %s", cfg.Auth.TokenEnv)
+		}
+		auth.Token = token
+	case cfg.Auth.TokenFile != "":
+		// not filepathx.FilePath.Read because it logs the content
+		token, err := os.ReadFile(cfg.Auth.TokenFile)
+		if err != nil && required {
+			return nil, errorx.Errorf(errors.Join(git.ErrNoToken, err), "read token file")
+		}
+		auth.Token = strings.TrimSpace(string(token))
+	}
+	logx.AddSecrets(auth.Secrets()...)
+	logx.Info("auth",
+		logx.S("username", auth.Username),
+		logx.B("token", auth.Token != ""),
+		logx.S("sshKey", auth.SSHKey),
+		logx.S("knownHosts", auth.KnownHosts),
+	)
+	return auth, nil
+}
+
 type commonResource struct {
 	cfg        *config.Config
 	env        execx.Env
 	gitCommand git.Command
 	workDir    filepathx.Path
+	// stage is not nil if worktree is enabled.
+	stage    *worktree.Stage
+	procLock *proclock.Lock
+}
+
+// release releases the resources held by prepareCommonResource.
+func (r *commonResource) release() {
+	if r.procLock == nil {
+		return
+	}
+	if err := r.procLock.Release(); err != nil {
+		logx.Error("release", logx.Err(err))
+	}
 }
 
 func (r *commonResource) lockFile() filepathx.FilePath {
 	return r.workDir.Join(r.cfg.LockFile).FilePath()
 }
 
+// localDir returns the directory the steps run in, the symlink to the installed worktree if worktree is enabled.
+func (r *commonResource) localDir() filepathx.DirPath {
+	return r.workDir.Join(r.cfg.LocalDir).DirPath()
+}
+
+// backupStorage returns the storage of the backups of the config.
+func (r *commonResource) backupStorage() backup.Storage {
+	s := backup.Storage{
+		Format: backup.FormatCopy,
+		Owner:  r.workDir.String(),
+	}
+	if b := r.cfg.Backup; b != nil {
+		// validated when parsing
+		s.Format, _ = backup.ParseFormat(b.Format)
+		switch {
+		case filepath.IsAbs(b.Dir):
+			s.Dir = b.Dir
+		case b.Dir != "":
+			s.Dir = r.workDir.Join(b.Dir).String()
+		}
+	}
+	return s
+}
+
+// matchWhen returns true if the when of the config matches the current platform.
+func (r *commonResource) matchWhen() bool {
+	platform := config.NewPlatform(r.env)
+	if r.cfg.When.Match(platform) {
+		return true
+	}
+	logx.Info("skip because when does not match",
+		logx.S("os", platform.OS),
+		logx.S("arch", platform.Arch),
+		logx.S("host", platform.Host),
+	)
+	return false
+}
+
 func prepareCommonResource(cmd *cobra.Command) (*commonResource, error) {
 	cfg, err := parseConfigFromFlag(cmd)
 	if err != nil {
@@ -160,13 +405,57 @@ func prepareCommonResource(cmd *cobra.Command) (*commonResource, error) {
 	if err != nil {
 		return nil, errorx.Errorf(err, "invalid workDir")
 	}
-	gitWorkDir := workDir.Join(cfg.LocalDir).DirPath()
-	gitCommand := git.NewCommand(git.NewCLI(gitWorkDir, env, gitCommandName))
+	localDir := workDir.Join(cfg.LocalDir)
+	gitWorkDir := localDir.DirPath()
+	if cfg.Worktree {
+		gitWorkDir = worktree.RepoDir(localDir)
+	}
+	offline, _ := cmd.Flags().GetBool("offline")
+	// uninstall and gc never touch the network
+	requireToken := !offline && cmd.Name() != "uninstall" && cmd.Name() != "gc"
+	auth, err := newAuth(cfg, env, requireToken)
+	if err != nil {
+		return nil, errorx.Errorf(err, "auth")
+	}
+	gitOpts := []git.ConfigOption{
+		git.WithFallbacks(cfg.URI.Fallbacks()),
+		git.WithAuth(auth),
+	}
+	useMirror, _ := cmd.Flags().GetBool("mirror")
+	if offline {
+		logx.Info("offline")
+		gitOpts = append(gitOpts, git.WithOffline(true))
+	}
+	if useMirror || offline {
+		mirrorDir, err := getMirrorDir(cmd)
+		if err != nil {
+			return nil, err
+		}
+		logx.Info("mirror", logx.S("dir", mirrorDir.String()))
+		mirrorEnv := execx.NewEnv()
+		mirrorEnv.Merge(env)
+		if auth != nil {
+			mirrorEnv.Merge(auth.Env(env))
+		}
+		gitOpts = append(gitOpts, git.WithMirror(mirror.NewCache(mirrorDir, mirrorEnv, gitCommandName)))
+	}
+	gitCommand := git.NewCommand(git.NewCLI(gitWorkDir, env, gitCommandName, gitOpts...), gitOpts...)
 	logx.Info("git", logx.S("git", gitCommandName), logx.S("workDir", gitWorkDir.String()))
+	var stage *worktree.Stage
+	if cfg.Worktree {
+		stage = worktree.NewStage(localDir, gitCommand.CLI())
+		logx.Info("worktree", logx.S("link", localDir.String()), logx.S("dir", worktree.Dir(localDir).String()))
+	}
+	procLock, err := acquireProcLock(cmd, workDir.DirPath())
+	if err != nil {
+		return nil, err
+	}
 	return &commonResource{
 		cfg:        cfg,
 		env:        env,
 		gitCommand: gitCommand,
 		workDir:    workDir,
+		stage:      stage,
+		procLock:   procLock,
 	}, nil
 }
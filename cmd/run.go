@@ -1,18 +1,25 @@
 package cmd
 
 import (
+	"berquerant/install-via-git-go/backup"
 	"berquerant/install-via-git-go/config"
 	"berquerant/install-via-git-go/errorx"
 	"berquerant/install-via-git-go/execx"
 	"berquerant/install-via-git-go/filepathx"
 	"berquerant/install-via-git-go/git"
 	"berquerant/install-via-git-go/gitlock"
+	"berquerant/install-via-git-go/history"
 	"berquerant/install-via-git-go/inspect"
+	"berquerant/install-via-git-go/journal"
 	"berquerant/install-via-git-go/lock"
 	"berquerant/install-via-git-go/logx"
 	"berquerant/install-via-git-go/runner"
 	"berquerant/install-via-git-go/strategy"
+	"berquerant/install-via-git-go/worktree"
 	"context"
+	"os"
+	"runtime"
+	"time"
 
 	"github.com/spf13/cobra"
 )
@@ -23,6 +30,7 @@ func init() {
 	runCmd.Flags().String("git", "git", "Git command")
 	runCmd.Flags().StringP("workDir", "w", ".", "Working directory")
 	fail(runCmd.MarkFlagDirname("workDir"))
+	setProcLockFlag(runCmd)
 	runCmd.Flags().BoolP("update", "u", false, "Force update")
 	runCmd.Flags().BoolP("retry", "r", false, "Continue even if no update")
 	runCmd.Flags().Bool("dry", false, "Execute up to strategy determination, no side effects")
@@ -30,6 +38,11 @@ func init() {
 	runCmd.Flags().Bool("clean", false, "Remove lockfile and repo before installation")
 	runCmd.Flags().Bool("noupdate", false, "Ignore lock and no update repo, just run scripts")
 	runCmd.Flags().Bool("backupRepo", false, "Backup repo dir")
+	runCmd.Flags().Bool("mirror", false, "Clone via shared mirror cache")
+	setMirrorDirFlag(runCmd)
+	runCmd.Flags().Bool("offline", false, "Never touch the network, use the local repo or the mirror cache only")
+	runCmd.Flags().Bool("changelog", false, "Show the commits and the diffstat from the lock to the new commit before install")
+	runCmd.Flags().Bool("confirm", false, "Show the changelog and ask before install if stdin is a terminal")
 	runCmd.MarkFlagsMutuallyExclusive("update", "retry", "clean", "noupdate")
 	rootCmd.AddCommand(runCmd)
 }
@@ -43,10 +56,15 @@ var runCmd = &cobra.Command{
 
 func newEnv(cfg *config.Config, cmd *cobra.Command) (execx.Env, error) {
 	env := execx.EnvFromMap(cfg.Env)
-	env.Set("IVG_URI", cfg.URI)
+	env.Set("IVG_URI", cfg.URI.Primary())
 	env.Set("IVG_BRANCH", cfg.Branch)
 	env.Set("IVG_LOCALD", cfg.LocalDir)
 	env.Set("IVG_LOCK", cfg.LockFile)
+	env.Set("IVG_OS", runtime.GOOS)
+	env.Set("IVG_ARCH", runtime.GOARCH)
+	if cfg.Profile != "" {
+		env.Set("IVG_PROFILE", cfg.Profile)
+	}
 	workDir, err := getPath(cmd, "workDir")
 	if err != nil {
 		return nil, errorx.Errorf(err, "invalid workDir")
@@ -60,11 +78,18 @@ func run(cmd *cobra.Command, _ []string) error {
 	if err != nil {
 		return err
 	}
+	defer common.release()
+	if !common.matchWhen() {
+		return nil
+	}
+	dry, _ := cmd.Flags().GetBool("dry")
+	if err := common.recoverInterrupted(cmd.Context(), dry); err != nil {
+		return err
+	}
 	// determine strategy
 	noupdate, _ := cmd.Flags().GetBool("noupdate")
 	clean, _ := cmd.Flags().GetBool("clean")
 	lockFile := common.lockFile()
-	dry, _ := cmd.Flags().GetBool("dry")
 	update, _ := cmd.Flags().GetBool("update")
 	retry, _ := cmd.Flags().GetBool("retry")
 	ius := &inspect.UpdateSpec{
@@ -74,7 +99,7 @@ func run(cmd *cobra.Command, _ []string) error {
 	}
 	fact := strategy.NewFact(
 		inspect.RepoExistence(cmd.Context(), common.gitCommand),
-		inspect.LockExistence(lockFile),
+		inspect.LockExistence(cmd.Context(), common.gitCommand, lockFile),
 		inspect.RepoStatus(cmd.Context(), common.gitCommand, lockFile),
 		ius.Get(),
 	)
@@ -83,13 +108,20 @@ func run(cmd *cobra.Command, _ []string) error {
 		commit, err := common.gitCommand.GetCommitHash(cmd.Context())
 		logx.Info("current hash", logx.S("hash", commit), logx.Err(err))
 	}
-	{
-		commit, err := lockFile.Read()
-		logx.Info("lock hash", logx.S("hash", commit), logx.Err(err))
-	}
+	content, err := lockFile.Read()
+	lockContent := lock.ParseContent(content)
+	logx.Info("lock hash",
+		logx.S("hash", lockContent.Hash),
+		logx.S("remote", lockContent.Remote),
+		logx.S("branch", lockContent.Branch),
+		logx.Err(err),
+	)
 
+	sourceKind, _, _ := config.DetectSource(common.cfg.URI.Primary())
 	logx.Info(
 		"strategy",
+		logx.SS("uri", common.cfg.URI),
+		logx.S("source", sourceKind.String()),
 		logx.S("lock", lockFile.String()),
 		logx.B("update", update),
 		logx.B("retry", retry),
@@ -102,46 +134,160 @@ func run(cmd *cobra.Command, _ []string) error {
 		logx.S("type", fact.SelectStrategy().String()),
 	)
 
+	offline, _ := cmd.Flags().GetBool("offline")
+	if offline && fact.SelectStrategy().RequiresNetwork() {
+		return errorx.Errorf(strategy.ErrNetworkRequired, "offline: strategy %s", fact.SelectStrategy())
+	}
+	// the config has no branch, record the detected one into the lock
+	var recordBranch string
+	if common.cfg.Branch == "" {
+		recordBranch = common.resolveBranch(cmd.Context(), fact.SelectStrategy(), offline, lockContent.Branch)
+	} else if fact.SelectStrategy().UsesBranch() && !offline {
+		common.checkBranch(cmd.Context())
+	}
+
 	if dry {
 		return nil
 	}
 
 	explicitCommit, _ := cmd.Flags().GetString("commit")
+	if explicitCommit != "" && fact.RExist == strategy.REexist {
+		// accept an abbreviated hash, a tag or a branch
+		if resolved, err := common.gitCommand.ResolveCommit(cmd.Context(), explicitCommit); err == nil {
+			logx.Info("resolve commit", logx.S("commit", explicitCommit), logx.S("resolved", resolved))
+			explicitCommit = resolved
+		}
+	}
+	storage := common.backupStorage()
 	backuperList := []runner.Backuper{
-		runner.NewLockFileBackup(lockFile, explicitCommit, clean),
+		// the lock is small enough to copy
+		runner.NewLockFileBackup(lockFile, explicitCommit, clean, backup.Storage{
+			Format: backup.FormatCopy,
+			Dir:    storage.Dir,
+			Owner:  storage.Owner,
+		}),
 	}
 	if backupRepo, _ := cmd.Flags().GetBool("backupRepo"); backupRepo {
-		backuperList = append(backuperList, runner.NewRepoBackup(common.gitCommand.CLI().Dir(), clean))
+		backuperList = append(backuperList, runner.NewRepoBackup(common.gitCommand.CLI().Dir(), clean, storage))
 	}
 	backupList := runner.NewBackupList(backuperList...)
 	if err := backupList.Create(); err != nil {
 		return errorx.Errorf(err, "create backup")
 	}
+	runJournal := &journal.Journal{
+		PID:     os.Getpid(),
+		Started: time.Now(),
+		Lock:    content,
+	}
+	for _, b := range backupList.Backups() {
+		runJournal.Backups = append(runJournal.Backups, journal.Backup{
+			Dir:    b.Dir().String(),
+			Origin: b.Origin().String(),
+			Format: string(b.Format()),
+		})
+	}
+	journalFile := journal.FromWorkDir(common.workDir.DirPath())
 
+	showChangelog, _ := cmd.Flags().GetBool("changelog")
+	confirm, _ := cmd.Flags().GetBool("confirm")
 	shell := getShell(cmd, common.cfg)
 	logx.Info("start installation!", logx.SS("shell", shell))
 	argument := &runner.Argument{
 		Config:       common.cfg,
 		Env:          common.env,
 		Shell:        shell,
-		LocalRepoDir: common.gitCommand.CLI().Dir(),
+		LocalRepoDir: common.localDir(),
 	}
 	installErr := (&installRunner{
-		Argument:   argument,
-		workDir:    common.workDir.DirPath(),
-		lockFile:   lockFile,
-		gitCommand: common.gitCommand,
-		fact:       fact,
-		noupdate:   noupdate,
+		Argument:     argument,
+		workDir:      common.workDir.DirPath(),
+		lockFile:     lockFile,
+		gitCommand:   common.gitCommand,
+		fact:         fact,
+		noupdate:     noupdate,
+		recordBranch: recordBranch,
+		changelog: &changelog{
+			gitCommand: common.gitCommand,
+			show:       showChangelog,
+			confirm:    confirm,
+			in:         os.Stdin,
+			out:        cmd.OutOrStdout(),
+		},
+		stage:       common.stage,
+		localDir:    argument.LocalRepoDir,
+		journal:     runJournal,
+		journalFile: journalFile,
 	}).run(cmd.Context())
 	if installErr != nil {
 		if err := backupList.Restore(); err != nil {
+			// keep the journal to recover on the next run
 			logx.Error("restore backup", logx.Err(err))
+			return installErr
+		}
+	}
+	if err := journalFile.Remove(); err != nil {
+		logx.Error("remove journal", logx.Err(err))
+	}
+	if installErr == nil {
+		// after the journal removed not to leave the journal referring the removed backups
+		if err := backupList.Close(); err != nil {
+			logx.Error("close backup", logx.Err(err))
 		}
 	}
 	return installErr
 }
 
+// resolveBranch sets the branch of the config and IVG_BRANCH, returns the branch to be recorded into the lock.
+//
+// The default branch of the remote is detected if the strategy clones, fetches or pulls the branch,
+// otherwise the branch recorded in the lock is used, falling back to main that is not recorded.
+func (r *commonResource) resolveBranch(ctx context.Context, st strategy.Type, offline bool, lockBranch string) string {
+	branch := func() string {
+		if st.UsesBranch() && !offline {
+			for _, uri := range r.cfg.URI {
+				branch, err := r.gitCommand.DefaultBranch(ctx, uri)
+				if err == nil {
+					logx.Info("detect default branch", logx.S("uri", uri), logx.S("branch", branch))
+					return branch
+				}
+				logx.Info("detect default branch", logx.S("uri", uri), logx.Err(err))
+			}
+		}
+		if lockBranch != "" {
+			logx.Info("branch from lock", logx.S("branch", lockBranch))
+			return lockBranch
+		}
+		return ""
+	}()
+	if branch == "" {
+		if st.UsesBranch() {
+			logx.Error("cannot detect default branch, use main")
+		} else {
+			// the strategy does not touch the branch, main is only for IVG_BRANCH
+			logx.Info("no default branch detected, use main", logx.S("strategy", st.String()))
+		}
+		r.cfg.Branch = "main"
+		r.env.Set("IVG_BRANCH", "main")
+		return ""
+	}
+	r.cfg.Branch = branch
+	r.env.Set("IVG_BRANCH", branch)
+	return branch
+}
+
+// checkBranch warns if the branch of the config does not exist on the primary remote.
+func (r *commonResource) checkBranch(ctx context.Context) {
+	uri := r.cfg.URI.Primary()
+	ok, err := r.gitCommand.HasRef(ctx, uri, r.cfg.Branch)
+	if err != nil {
+		logx.Debug("check branch", logx.S("uri", uri), logx.Err(err))
+		return
+	}
+	if !ok {
+		logx.Error("branch not found in remote", logx.S("uri", uri), logx.S("branch", r.cfg.Branch))
+	}
+}
+
 type installRunner struct {
 	*runner.Argument
 	workDir    filepathx.DirPath
@@ -149,62 +295,185 @@ type installRunner struct {
 	gitCommand git.Command
 	fact       strategy.Fact
 	noupdate   bool
+	// recordBranch is recorded into the lock if not empty.
+	recordBranch string
+	changelog    *changelog
+	// stage is not nil if worktree is enabled.
+	stage *worktree.Stage
+	// staged is the hash of the worktree to be swapped to after install.
+	staged string
+	// localDir is the original LocalRepoDir.
+	localDir    filepathx.DirPath
+	journal     *journal.Journal
+	journalFile *journal.File
+}
+
+// transition records the phase into the journal before entering it.
+func (r *installRunner) transition(phase journal.Phase) {
+	r.journal.Phase = phase
+	if err := r.journalFile.Write(r.journal); err != nil {
+		logx.Error("write journal", logx.Err(err))
+	}
+}
+
+// journalHook returns the runner.Hook to record the next commit of pair before install.
+func (r *installRunner) journalHook(pair *lock.Pair) runner.Hook {
+	return func(context.Context) error {
+		r.journal.Next = pair.Next
+		r.journal.Staged = r.staged
+		r.transition(journal.PhaseInstall)
+		return nil
+	}
+}
+
+// stageHook returns the runner.Hook to checkout the next commit of pair into its worktree,
+// and to install there instead of LocalRepoDir.
+func (r *installRunner) stageHook(pair *lock.Pair) runner.Hook {
+	return func(ctx context.Context) error {
+		if r.stage == nil || pair.Next == "" {
+			return nil
+		}
+		if current, ok := r.stage.Current(); ok && current == pair.Next {
+			// reinstall the installed one
+			return nil
+		}
+		dir, err := r.stage.Add(ctx, pair.Next)
+		if err != nil {
+			return err
+		}
+		r.staged = pair.Next
+		r.LocalRepoDir = dir
+		return nil
+	}
 }
 
 func (r *installRunner) run(ctx context.Context) error {
+	start := time.Now()
 	if err := r.workDir.Ensure(); err != nil {
 		return errorx.Errorf(err, "ensure workDir")
 	}
-	if err := r.LocalRepoDir.Parent().DirPath().Ensure(); err != nil {
+	if err := r.gitCommand.CLI().Dir().Parent().DirPath().Ensure(); err != nil {
 		return errorx.Errorf(err, "ensure git workDir")
 	}
+	r.transition(journal.PhaseSetup)
 
 	logx.Info("check")
-	if _, err := execx.NewExecutorFromStrings(r.Config.Steps.Check, r.Shell...).
+	if _, err := r.Executor(r.Config.Steps.Check).
 		Execute(ctx, execx.WithEnv(r.Env), execx.WithDir(r.workDir)); err != nil {
 		logx.Info("cancel installation because check failed", logx.Err(err))
+		r.recordUnchanged(start, history.OutcomeCanceled, errorx.Errorf(err, "check"))
 		return nil
 	}
 
 	logx.Info("setup")
-	if _, err := execx.NewExecutorFromStrings(r.Config.Steps.Setup, r.Shell...).
+	if _, err := r.Executor(r.Config.Steps.Setup).
 		Execute(ctx, execx.WithEnv(r.Env), execx.WithDir(r.workDir)); err != nil {
-		return errorx.Errorf(err, "setup")
+		err = errorx.Errorf(err, "setup")
+		r.recordUnchanged(start, history.OutcomeFailure, err)
+		return err
 	}
 
 	if err := r.lockFile.Ensure(); err != nil {
-		return errorx.Errorf(err, "ensure lockfile")
+		err = errorx.Errorf(err, "ensure lockfile")
+		r.recordUnchanged(start, history.OutcomeFailure, err)
+		return err
 	}
 
 	keeper := gitlock.NewGitKeeper(lock.NewFileKeeper(r.lockFile), r.gitCommand)
+	if pair := keeper.Locker().Pair(); r.fact.LExist == strategy.LEnone && pair.Current != "" {
+		// invalid lock, not to record it as the previous or rollback to it
+		logx.Info("ignore lock", logx.S("hash", pair.Current))
+		pair.Current = ""
+	}
 
 	logx.Info("run strategy", logx.S("type", r.fact.SelectStrategy().String()))
+	r.transition(journal.PhaseStrategy)
 	err := runner.NewStrategy(
 		r.Argument,
 		r.fact.SelectStrategy().Runner(strategy.NewRunnerConfig(
-			r.Config.URI,
+			r.Config.URI.Primary(),
 			r.Config.Branch,
 			keeper.Locker().Pair(),
 			r.gitCommand,
 		)),
+		r.changelog.hook(keeper.Locker().Pair()),
+		r.stageHook(keeper.Locker().Pair()),
+		r.journalHook(keeper.Locker().Pair()),
 	).Run(ctx)
+	if err == nil && r.staged != "" {
+		err = r.stage.Swap(r.staged)
+	}
 
 	if err == nil {
 		if r.noupdate {
 			return nil
 		}
+		if len(r.Config.URI) > 1 {
+			// record which remote provided the commit
+			keeper.Locker().Pair().Remote = r.gitCommand.Provider()
+		}
+		keeper.Locker().Pair().Branch = r.recordBranch
+		r.journal.Next = keeper.Locker().Pair().Next
+		r.transition(journal.PhaseCommit)
 		if err := keeper.Commit(); err != nil {
 			return errorx.Errorf(err, "commit")
 		}
+		outcome := history.OutcomeSuccess
+		if r.fact.SelectStrategy() == strategy.Tnoop {
+			outcome = history.OutcomeNoop
+		}
+		r.record(start, keeper.Locker().Pair(), outcome, nil)
 		return nil
 	}
 
 	// failed to run strategy
 	logx.Error("run strategy", logx.Err(err))
+	r.transition(journal.PhaseRollback)
+	r.LocalRepoDir = r.localDir
 	_ = runner.NewRollback(
 		r.Argument,
 		keeper,
 		r.noupdate,
 	).Run(ctx)
+	if r.staged != "" {
+		if err := r.stage.Remove(ctx, r.staged); err != nil {
+			logx.Error("remove worktree", logx.Err(err))
+		}
+	}
+	if !r.noupdate {
+		r.record(start, keeper.Locker().Pair(), history.OutcomeRollback, err)
+	}
 	return err
 }
+
+// record appends the result of the installation to the history file.
+func (r *installRunner) record(start time.Time, pair *lock.Pair, outcome history.Outcome, err error) {
+	recordHistory(r.workDir, &history.Entry{
+		Time:     start,
+		URI:      r.Config.URI.Primary(),
+		Strategy: r.fact.SelectStrategy().String(),
+		From:     pair.Current,
+		To:       pair.Next,
+		Outcome:  outcome,
+		Duration: history.Duration(time.Since(start)),
+		Changes:  r.changelog.changes,
+	}, err)
+}
+
+// recordUnchanged records outcome before running the strategy, the lock is not changed.
+func (r *installRunner) recordUnchanged(start time.Time, outcome history.Outcome, err error) {
+	if r.noupdate {
+		return
+	}
+	r.record(start, lock.NewFileKeeper(r.lockFile).Pair(), outcome, err)
+}
+
+// recordHistory appends entry to the history file of workDir, with the message of err if not nil.
+func recordHistory(workDir filepathx.DirPath, entry *history.Entry, err error) {
+	if err != nil {
+		entry.Error = logx.Redact(err.Error())
+	}
+	if err := history.FromWorkDir(workDir).Append(entry); err != nil {
+		logx.Error("record history", logx.Err(err))
+	}
+}
@@ -0,0 +1,34 @@
+package cmd
+
+import (
+	"berquerant/install-via-git-go/config"
+	"encoding/json"
+
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(schemaCmd)
+}
+
+var schemaCmd = &cobra.Command{
+	Use:   "schema",
+	Short: "Generate JSON Schema of config",
+	Long: `Generate JSON Schema of config.
+
+For the editors, e.g. yaml-language-server:
+
+  install-via-git schema > ivg.schema.json
+
+and add the modeline to the config:
+
+  # yaml-language-server: $schema=ivg.schema.json`,
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		v, err := json.MarshalIndent(config.NewSchema(), "", "  ")
+		if err != nil {
+			return err
+		}
+		cmd.Println(string(v))
+		return nil
+	},
+}
@@ -61,16 +61,42 @@ const skeleton = `# install-via-git configuration.
 # - "remove" cli option
 # - "purge" cli option
 #
-# repository uri
+# uri, branch, locald, lock and backup.dir can refer ${NAME} variables, expanded when parsing the config.
+# NAME is looked up in IVG_WORKD (run, uninstall), env and the process environment in order.
+# Undefined ${NAME} is left as is, or an error with --strict. $${NAME} is written as ${NAME}.
+# The steps are not expanded, the shell expands env, IVG_* and the process environment when running them.
+#
+# base configs to merge (optional), a path or a list of paths.
+# relative paths are resolved from the directory of this config.
+# the bases are merged in order, then this config is merged on top of them:
+# scalars, lists (uri, shell, secrets, each step) and auth are replaced if set, env is merged per key.
+# "parse --origin" shows the file each field comes from.
+# extends:
+#   - ../common.yml
+# repository uri.
+# remote urls (https://..., ssh://..., git@host:path, host:path), file:// urls,
+# local repository directories and git bundle files are available.
+# relative local paths are resolved from the directory of the config defining uri, the current directory if stdin.
+# uri can be a list, the first is the primary and the rest are the fallback mirrors,
+# clone, fetch and pull try them in order and the lock records which one provided the commit.
 uri: https://github.com/some/toolname.git
-# target branch name (optional, default is main)
+# target branch name (optional).
+# default is the default branch of the remote (HEAD), detected on clone and fetch and recorded in the lock.
+# a warning is logged if the branch does not exist on the remote.
 branch: master
 # git clone destination (optional, default is repo).
 # clone to workDir/locald.
 locald: localrepo
 # file to store commit hash (optional, default is lock).
 # empty file is assumed to not exist
+# an abbreviated hash, a tag or a branch written by hand is resolved and replaced with the full hash,
+# the value the local repository cannot resolve is ignored.
 lock: lockfile
+# install each commit into its own git worktree (optional, default is false).
+# the repository is cloned to workDir/locald.d/.repo and install runs in workDir/locald.d/HASH,
+# then workDir/locald is swapped to the symlink to it only if install succeeded.
+# rollback subcommand swaps the symlink back without install if the worktree exists.
+# worktree: true
 # shell to execute scripts (setup, install, ...) (optional).
 # command line "--shell" overrides this.
 shell:
@@ -78,13 +104,55 @@ shell:
 # environment variables (optional).
 # check, setup, install, rollback, skip can refer the following variables:
 # - IVG_URI=value of repository
-# - IVG_BRANCH=value of branch
+# - IVG_BRANCH=value of branch, or the detected branch
 # - IVG_LOCALD=value of locald
 # - IVG_LOCK=value of lock
+# - IVG_OS=GOOS, e.g. linux, darwin
+# - IVG_ARCH=GOARCH, e.g. amd64, arm64
+# - IVG_PROFILE=name of the profile, if selected
 # install can refer the following variables:
 # - IVG_WORKD=absolute path of workDir
 env:
   MY_NAME: myname
+# patterns of env keys whose values are secrets (optional).
+# the values are masked in the logs, the outputs of the scripts and parse.
+# *_TOKEN, *_PASSWORD and *_SECRET are always secrets.
+secrets:
+  - "*_APIKEY"
+# credentials for private repositories (optional).
+# applied only to git, not to check, setup, install, ...
+# auth:
+#   # environment variable of the access token for https, from env or the process environment.
+#   # sent only to the primary uri, not required by uninstall, gc and --offline.
+#   # tokenFile instead reads the token from the file.
+#   tokenEnv: GITHUB_TOKEN
+#   # username sent with the token (optional, default is x-access-token)
+#   username: x-access-token
+#   # private key for ssh (optional)
+#   sshKey: ~/.ssh/id_ed25519
+#   # known_hosts file for ssh (optional)
+#   knownHosts: ~/.ssh/known_hosts
+# storage of the backups of --backupRepo (optional).
+# backup:
+#   # copy (default) copies the repo file by file, tar.gz writes a gzipped tar, smaller and faster for big repos.
+#   format: tar.gz
+#   # directory to create the backups in, relative to workDir (optional, default is the system temp dir).
+#   dir: /var/tmp/install-via-git
+# condition to install (optional), skip run and uninstall if not matched.
+# each field matches if any of the values matches, when matches if all the fields match.
+# when:
+#   # GOOS values
+#   os: [linux, darwin]
+#   # GOARCH values
+#   arch: [amd64, arm64]
+#   # glob patterns of the hostname
+#   host: "*"
+#   # environment variables to be set, in env or the process environment
+#   env: CI
+#
+# the steps below are lists of scripts.
+# a script can also be a mapping of run and when, run only if when matches.
+#
 # check will always run in workDir (optional)
 # cancel installation when returning a failure exit status
 check:
@@ -95,6 +163,10 @@ setup:
 # install will run when installation is required in workDir/locald (optional)
 install:
   - echo "Start install"
+  - run: echo "Start install on linux arm64"
+    when:
+      os: linux
+      arch: arm64
 # rollback will run when an error occurs in workDir/locald (optional)
 rollback:
   - echo "Start rollback"
@@ -103,4 +175,14 @@ skip:
   - echo "Start skip"
 # uninstall will run by uninstall subcommand (optional)
 uninstall:
-  - echo "Start uninstall"`
+  - echo "Start uninstall"
+# named overrides (optional), selected by --profile or IVG_PROFILE, IVG_PROFILE is ignored if not defined here.
+# the profile is merged over this config by the same rules as extends.
+# uri, branch, locald, lock, env, shell, auth, secrets, when and the steps are available.
+profiles:
+  ci:
+    branch: develop
+    env:
+      CI: "true"
+    install:
+      - echo "Start install on CI"`
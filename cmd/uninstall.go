@@ -10,7 +10,10 @@ import (
 	"berquerant/install-via-git-go/logx"
 	"berquerant/install-via-git-go/runner"
 	"berquerant/install-via-git-go/strategy"
+	"berquerant/install-via-git-go/worktree"
 	"context"
+	"errors"
+	"os"
 
 	"github.com/spf13/cobra"
 )
@@ -21,6 +24,7 @@ func init() {
 	uninstallCmd.Flags().String("git", "git", "Git command")
 	uninstallCmd.Flags().StringP("workDir", "w", ".", "Working directory")
 	fail(uninstallCmd.MarkFlagDirname("workDir"))
+	setProcLockFlag(uninstallCmd)
 	uninstallCmd.Flags().Bool("dry", false, "Execute up to strategy determination, no side effects")
 	uninstallCmd.Flags().Bool("remove", false, "Remove repo")
 	uninstallCmd.Flags().Bool("purge", false, "Remove repo and clear lock")
@@ -40,11 +44,18 @@ func uninstall(cmd *cobra.Command, _ []string) error {
 	if err != nil {
 		return err
 	}
+	defer common.release()
+	if !common.matchWhen() {
+		return nil
+	}
+	dry, _ := cmd.Flags().GetBool("dry")
+	if err := common.recoverInterrupted(cmd.Context(), dry); err != nil {
+		return err
+	}
 	lockFile := common.lockFile()
 
 	remove, _ := cmd.Flags().GetBool("remove")
 	purge, _ := cmd.Flags().GetBool("purge")
-	dry, _ := cmd.Flags().GetBool("dry")
 
 	ius := &inspect.UpdateSpec{
 		Uninstall: true,
@@ -52,7 +63,7 @@ func uninstall(cmd *cobra.Command, _ []string) error {
 	}
 	fact := strategy.NewFact(
 		inspect.RepoExistence(cmd.Context(), common.gitCommand),
-		inspect.LockExistence(lockFile),
+		inspect.LockExistence(cmd.Context(), common.gitCommand, lockFile),
 		inspect.RepoStatus(cmd.Context(), common.gitCommand, lockFile),
 		ius.Get(),
 	)
@@ -75,7 +86,7 @@ func uninstall(cmd *cobra.Command, _ []string) error {
 		Config:       common.cfg,
 		Env:          common.env,
 		Shell:        shell,
-		LocalRepoDir: common.gitCommand.CLI().Dir(),
+		LocalRepoDir: common.localDir(),
 	}
 	return (&uninstallRunner{
 		Argument:   argument,
@@ -84,6 +95,7 @@ func uninstall(cmd *cobra.Command, _ []string) error {
 		gitCommand: common.gitCommand,
 		fact:       fact,
 		purge:      purge,
+		stage:      common.stage,
 	}).run(cmd.Context())
 }
 
@@ -94,6 +106,8 @@ type uninstallRunner struct {
 	gitCommand git.Command
 	fact       strategy.Fact
 	purge      bool
+	// stage is not nil if worktree is enabled.
+	stage *worktree.Stage
 }
 
 func (r *uninstallRunner) run(ctx context.Context) error {
@@ -103,7 +117,7 @@ func (r *uninstallRunner) run(ctx context.Context) error {
 	if err := runner.NewUninstall(
 		r.Argument,
 		r.fact.SelectStrategy().Runner(strategy.NewRunnerConfig(
-			r.Config.URI,
+			r.Config.URI.Primary(),
 			r.Config.Branch,
 			keeper.Locker().Pair(),
 			r.gitCommand,
@@ -112,6 +126,16 @@ func (r *uninstallRunner) run(ctx context.Context) error {
 		return err
 	}
 
+	if r.stage != nil && r.fact.SelectStrategy() == strategy.Tremove {
+		logx.Info("remove worktrees", logx.S("link", r.stage.Link().String()))
+		if err := os.Remove(r.stage.Link().String()); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return errorx.Errorf(err, "remove %s", r.stage.Link())
+		}
+		if err := worktree.Dir(r.stage.Link()).Remove(); err != nil {
+			return errorx.Errorf(err, "remove worktrees")
+		}
+	}
+
 	if r.purge {
 		logx.Info("clear lock")
 		if err := keeper.Locker().Clear(); err != nil {
@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"berquerant/install-via-git-go/config"
+	"berquerant/install-via-git-go/errorx"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	setConfigFlag(validateCmd)
+	rootCmd.AddCommand(validateCmd)
+}
+
+var validateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Validate config file",
+	Long: `Validate config file.
+
+Report all the problems of the config at once:
+unknown keys with the positions, invalid uri, shell not found,
+locald or lock out of workDir, empty steps and so on.
+Exit with non-zero status if any problem is found.`,
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		cfgFile, _ := cmd.Flags().GetString("config")
+		parseOpt, err := parseOptionFromFlag(cmd)
+		if err != nil {
+			return err
+		}
+
+		var r io.Reader = os.Stdin
+		if cfgFile != "-" {
+			file, release, err := resolveConfigFile(cmd.Context(), cfgFile, configAuthFromFlag(cmd))
+			if err != nil {
+				return err
+			}
+			defer release()
+			f, err := os.Open(file)
+			if err != nil {
+				return errorx.Errorf(err, "load config file %s", cfgFile)
+			}
+			defer f.Close()
+			r = f
+			parseOpt = append(parseOpt, config.WithPath(file))
+		}
+
+		errs := config.Validate(r, parseOpt...)
+		for _, err := range errs {
+			cmd.Println(err)
+		}
+		if len(errs) > 0 {
+			return errorx.Errorf(config.ErrInvalid, "%d problems in %s", len(errs), cfgFile)
+		}
+		return nil
+	},
+}
@@ -1,37 +1,140 @@
 package config
 
 import (
+	"berquerant/install-via-git-go/backup"
 	"berquerant/install-via-git-go/errorx"
+	"berquerant/install-via-git-go/logx"
 	"errors"
+	"fmt"
 	"io"
-
-	"github.com/goccy/go-yaml"
+	"path"
+	"path/filepath"
 )
 
 type (
 	Config struct {
-		URI      string            `yaml:"uri" json:"uri"`
+		// Extends is the base configs, merged in order before this config.
+		Extends  Strings `yaml:"extends,omitempty" json:"extends,omitempty"`
+		URI      URIs    `yaml:"uri" json:"uri"`
+		Branch   string  `yaml:"branch,omitempty" json:"branch,omitempty"`
+		LocalDir string  `yaml:"locald,omitempty" json:"locald,omitempty"`
+		LockFile string  `yaml:"lock,omitempty" json:"lock,omitempty"`
+		// Worktree checkouts each commit into its own worktree locald.d/HASH,
+		// installs there and swaps the symlink locald to it on success.
+		Worktree bool              `yaml:"worktree,omitempty" json:"worktree,omitempty"`
+		Steps    Steps             `yaml:"steps,inline" json:"steps"`
+		Env      map[string]string `yaml:"env,omitempty" json:"env,omitempty"`
+		Shell    []string          `yaml:"shell,omitempty" json:"shell,omitempty"`
+		Auth     *Auth             `yaml:"auth,omitempty" json:"auth,omitempty"`
+		// Backup is the storage of the backups of --backupRepo.
+		Backup *Backup `yaml:"backup,omitempty" json:"backup,omitempty"`
+		// Secrets are the patterns of the env keys whose values are masked in the logs,
+		// in addition to *_TOKEN, *_PASSWORD and *_SECRET.
+		Secrets []string `yaml:"secrets,omitempty" json:"secrets,omitempty"`
+		// When is the condition to install, skip the whole config if not matched.
+		When *When `yaml:"when,omitempty" json:"when,omitempty"`
+		// Profiles are the named overrides, selected by Profile option of Parse.
+		Profiles map[string]*Profile `yaml:"profiles,omitempty" json:"profiles,omitempty"`
+		// Profile is the name of the applied profile.
+		Profile string `yaml:"-" json:"-"`
+		// Origin is the file each field comes from, keyed by the yaml path like $.uri, $.env.KEY.
+		Origin map[string]string `yaml:"-" json:"-"`
+	}
+
+	// Profile overrides the config.
+	// The fields are merged over the config as the config extending the config.
+	Profile struct {
+		URI      URIs              `yaml:"uri,omitempty" json:"uri,omitempty"`
 		Branch   string            `yaml:"branch,omitempty" json:"branch,omitempty"`
 		LocalDir string            `yaml:"locald,omitempty" json:"locald,omitempty"`
 		LockFile string            `yaml:"lock,omitempty" json:"lock,omitempty"`
 		Steps    Steps             `yaml:"steps,inline" json:"steps"`
 		Env      map[string]string `yaml:"env,omitempty" json:"env,omitempty"`
 		Shell    []string          `yaml:"shell,omitempty" json:"shell,omitempty"`
+		Auth     *Auth             `yaml:"auth,omitempty" json:"auth,omitempty"`
+		Secrets  []string          `yaml:"secrets,omitempty" json:"secrets,omitempty"`
+		When     *When             `yaml:"when,omitempty" json:"when,omitempty"`
+	}
+
+	// Auth is the credentials for the private repositories.
+	// Applied only to git invocations, not to the steps.
+	Auth struct {
+		// TokenEnv is the name of the environment variable of the access token for https.
+		TokenEnv string `yaml:"tokenEnv,omitempty" json:"tokenEnv,omitempty"`
+		// TokenFile is the file of the access token for https.
+		TokenFile string `yaml:"tokenFile,omitempty" json:"tokenFile,omitempty"`
+		// Username is sent with the token, default is x-access-token.
+		Username string `yaml:"username,omitempty" json:"username,omitempty"`
+		// SSHKey is the private key file for ssh.
+		SSHKey string `yaml:"sshKey,omitempty" json:"sshKey,omitempty"`
+		// KnownHosts is the known_hosts file for ssh.
+		KnownHosts string `yaml:"knownHosts,omitempty" json:"knownHosts,omitempty"`
+	}
+
+	// Backup is the storage of the backups.
+	Backup struct {
+		// Format is copy (default), the file-by-file copy, or tar.gz, the gzipped tar.
+		Format string `yaml:"format,omitempty" json:"format,omitempty"`
+		// Dir is the directory to create the backups in, default is the system temp dir.
+		Dir string `yaml:"dir,omitempty" json:"dir,omitempty"`
 	}
 
 	Steps struct {
-		Setup     []string `yaml:"setup,omitempty" json:"setup,omitempty"`
-		Install   []string `yaml:"install,omitempty" json:"install,omitempty"`
-		Rollback  []string `yaml:"rollback,omitempty" json:"rollback,omitempty"`
-		Skip      []string `yaml:"skip,omitempty" json:"skip,omitempty"`
-		Check     []string `yaml:"check,omitempty" json:"check,omitempty"`
-		Uninstall []string `yaml:"uninstall,omitempty" json:"uninstall,omitempty"`
+		Setup     []Step `yaml:"setup,omitempty" json:"setup,omitempty"`
+		Install   []Step `yaml:"install,omitempty" json:"install,omitempty"`
+		Rollback  []Step `yaml:"rollback,omitempty" json:"rollback,omitempty"`
+		Skip      []Step `yaml:"skip,omitempty" json:"skip,omitempty"`
+		Check     []Step `yaml:"check,omitempty" json:"check,omitempty"`
+		Uninstall []Step `yaml:"uninstall,omitempty" json:"uninstall,omitempty"`
 	}
 )
 
+func (p *Profile) config() *Config {
+	return &Config{
+		URI:      p.URI,
+		Branch:   p.Branch,
+		LocalDir: p.LocalDir,
+		LockFile: p.LockFile,
+		Steps:    p.Steps,
+		Env:      p.Env,
+		Shell:    p.Shell,
+		Auth:     p.Auth,
+		Secrets:  p.Secrets,
+		When:     p.When,
+	}
+}
+
+// originDir returns the absolute directory of the config file origin, empty if stdin.
+func originDir(origin string) string {
+	if origin == "" || origin == stdinOrigin {
+		return ""
+	}
+	dir, err := filepath.Abs(filepath.Dir(origin))
+	if err != nil {
+		return ""
+	}
+	return dir
+}
+
+// namedSteps is a kind of the steps.
+type namedSteps struct {
+	name  string
+	steps *[]Step
+}
+
+func (s *Steps) all() []namedSteps {
+	return []namedSteps{
+		{name: "setup", steps: &s.Setup},
+		{name: "install", steps: &s.Install},
+		{name: "rollback", steps: &s.Rollback},
+		{name: "skip", steps: &s.Skip},
+		{name: "check", steps: &s.Check},
+		{name: "uninstall", steps: &s.Uninstall},
+	}
+}
+
 func defaultConfig() Config {
 	return Config{
-		Branch:   "main",
 		LockFile: "lock",
 		LocalDir: "repo",
 	}
@@ -42,19 +145,108 @@ var (
 	ErrInvalid = errors.New("Invalid")
 )
 
-func Parse(r io.Reader) (*Config, error) {
+//go:generate go tool goconfig -field "Strict bool|Vars map[string]string|Path string|Format string|Profile string|DefaultProfile string" -prefix Parse -option -output parse_config_generated.go
+
+// Parse reads the config, merges the configs it extends and expands ${NAME} variables.
+// Unknown keys are errors.
+//
+// Vars option adds the variables, e.g. IVG_WORKD.
+// Strict option makes undefined variables an error.
+// Path option is the path of the config, relative extends are resolved from its directory.
+// Format option is the format of the config, detected from the extension of Path or the content if empty.
+// Profile option is the name of the profile to merge over the config.
+// DefaultProfile option is the profile used if Profile is empty, ignored if the config does not have it.
+func Parse(r io.Reader, opt ...ParseConfigOption) (*Config, error) {
+	cfg, errs, err := parse(r, opt...)
+	if err != nil {
+		return nil, err
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return cfg, nil
+}
+
+// parse reads the config and returns the problems of it.
+// Returns an error if the config cannot be read.
+func parse(r io.Reader, opt ...ParseConfigOption) (*Config, []error, error) {
+	parseConfig := NewParseConfigBuilder().Strict(false).Vars(nil).Path("").Format("").Profile("").DefaultProfile("").Build()
+	parseConfig.Apply(opt...)
+
 	bytes, err := io.ReadAll(r)
 	if err != nil {
-		return nil, errors.Join(ErrParse, err)
+		return nil, nil, errors.Join(ErrParse, err)
+	}
+
+	file := stdinOrigin
+	l := loader{
+		format: parseConfig.Format.Get(),
+	}
+	if p := parseConfig.Path.Get(); p != "" {
+		abs, err := filepath.Abs(p)
+		if err != nil {
+			return nil, nil, errors.Join(ErrParse, err)
+		}
+		file = p
+		l.stack = []string{abs}
+	}
+	merged, origin, err := l.load(file, bytes)
+	if err != nil {
+		return nil, nil, err
+	}
+	cfg := *merged
+	cfg.Origin = origin
+	// relative local uri is resolved from the directory of the config it comes from
+	uriDir := originDir(origin["$.uri"])
+	name := parseConfig.Profile.Get()
+	if name == "" {
+		name = parseConfig.DefaultProfile.Get()
+		if _, ok := cfg.Profiles[name]; name != "" && !ok {
+			logx.Debug("ignore unknown default profile", logx.S("profile", name))
+			name = ""
+		}
 	}
+	if name != "" {
+		p, ok := cfg.Profiles[name]
+		if !ok {
+			return nil, nil, errorx.Errorf(ErrInvalid, "unknown profile %s", name)
+		}
+		if len(p.URI) > 0 {
+			uriDir = originDir(origin["$.profiles."+name])
+		}
+		profileOrigin := fmt.Sprintf("%s (profile %s)", origin["$.profiles."+name], name)
+		mergeConfig(&cfg, origin, p.config(), func(string) string { return profileOrigin })
+		cfg.Profile = name
+	}
+	applyDefaults(&cfg)
 
-	cfg := defaultConfig()
-	if err := yaml.Unmarshal(bytes, &cfg); err != nil {
-		return nil, errors.Join(ErrParse, err)
+	errs := l.errs
+	if err := interpolate(&cfg, parseConfig.Vars.Get(), parseConfig.Strict.Get()); err != nil {
+		errs = append(errs, err)
 	}
 
-	if cfg.URI == "" {
-		return nil, errorx.Errorf(ErrInvalid, "empty uri")
+	if len(cfg.URI) == 0 {
+		errs = append(errs, errorx.Errorf(ErrInvalid, "empty uri"))
+	}
+	for i, x := range cfg.URI {
+		if x == "" {
+			errs = append(errs, errorx.Errorf(ErrInvalid, "empty uri[%d]", i))
+			continue
+		}
+		cfg.URI[i] = resolveSource(x, uriDir)
+	}
+	if a := cfg.Auth; a != nil && a.TokenEnv != "" && a.TokenFile != "" {
+		errs = append(errs, errorx.Errorf(ErrInvalid, "auth: both tokenEnv and tokenFile"))
+	}
+	if b := cfg.Backup; b != nil {
+		if _, err := backup.ParseFormat(b.Format); err != nil {
+			errs = append(errs, errorx.Errorf(errors.Join(ErrInvalid, err), "backup: format"))
+		}
+	}
+	for _, p := range cfg.Secrets {
+		if _, err := path.Match(p, ""); err != nil {
+			errs = append(errs, errorx.Errorf(ErrInvalid, "secrets: %s: %v", p, err))
+		}
 	}
-	return &cfg, nil
+	return &cfg, errs, nil
 }
@@ -0,0 +1,66 @@
+package config_test
+
+import (
+	"berquerant/install-via-git-go/config"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	for _, tc := range []struct {
+		title   string
+		input   string
+		want    *config.Config
+		wantErr error
+	}{
+		{
+			title:   "empty uri",
+			input:   `branch: main`,
+			wantErr: config.ErrInvalid,
+		},
+		{
+			title: "uri",
+			input: `uri: https://example.com/repo.git`,
+			want: &config.Config{
+				URI:      config.URIs{"https://example.com/repo.git"},
+				LocalDir: "repo",
+				LockFile: "lock",
+				Origin:   map[string]string{"$.uri": "-"},
+			},
+		},
+		{
+			title: "uri list",
+			input: `uri:
+  - https://example.com/repo.git
+  - https://mirror.example.com/repo.git`,
+			want: &config.Config{
+				URI: config.URIs{
+					"https://example.com/repo.git",
+					"https://mirror.example.com/repo.git",
+				},
+				LocalDir: "repo",
+				LockFile: "lock",
+				Origin:   map[string]string{"$.uri": "-"},
+			},
+		},
+		{
+			title: "empty uri in list",
+			input: `uri:
+  - https://example.com/repo.git
+  - ""`,
+			wantErr: config.ErrInvalid,
+		},
+	} {
+		t.Run(tc.title, func(t *testing.T) {
+			got, err := config.Parse(strings.NewReader(tc.input))
+			if tc.wantErr != nil {
+				assert.ErrorIs(t, err, tc.wantErr)
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
@@ -0,0 +1,175 @@
+package config
+
+import (
+	"berquerant/install-via-git-go/errorx"
+	"errors"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/goccy/go-yaml"
+)
+
+// stdinOrigin is the origin of the fields read from stdin.
+const stdinOrigin = "-"
+
+// loader reads the config and the configs it extends.
+type loader struct {
+	// stack is the absolute paths of the configs being loaded, to detect cycles.
+	stack []string
+	// errs is the unknown keys of the configs.
+	errs []error
+	// format is the format of the first config, detected if empty.
+	// The extended configs are always detected.
+	format string
+}
+
+// load unmarshals the config read from file and merges the configs it extends.
+// The config can be YAML, JSON or TOML.
+//
+// The extended configs are merged in order, then the config itself is merged on top of them.
+// Relative paths of extends are resolved from the directory of file, or the working directory if stdin.
+func (l *loader) load(file string, content []byte) (*Config, map[string]string, error) {
+	format := l.format
+	l.format = ""
+	if format == "" {
+		format = DetectFormat(file, content)
+	}
+	bytes, positional, err := toYAML(format, content)
+	if err != nil {
+		return nil, nil, errorx.Errorf(err, "%s", file)
+	}
+
+	var c Config
+	if err := yaml.Unmarshal(bytes, &c); err != nil {
+		return nil, nil, errors.Join(ErrParse, errorx.Errorf(err, "%s", file))
+	}
+	errs, err := unknownKeys(file, bytes, positional)
+	if err != nil {
+		return nil, nil, err
+	}
+	l.errs = append(l.errs, errs...)
+
+	dir := "."
+	if file != stdinOrigin {
+		dir = filepath.Dir(file)
+	}
+	var (
+		merged Config
+		origin = map[string]string{}
+	)
+	for _, x := range c.Extends {
+		if x == "" {
+			return nil, nil, errorx.Errorf(ErrInvalid, "%s: empty extends", file)
+		}
+		p := x
+		if !filepath.IsAbs(p) {
+			p = filepath.Join(dir, p)
+		}
+		p, err := filepath.Abs(p)
+		if err != nil {
+			return nil, nil, errors.Join(ErrParse, err)
+		}
+		if slices.Contains(l.stack, p) {
+			return nil, nil, errorx.Errorf(ErrInvalid, "extends cycle: %s",
+				strings.Join(append(l.stack, p), " -> "))
+		}
+		b, err := os.ReadFile(p)
+		if err != nil {
+			return nil, nil, errors.Join(ErrParse, errorx.Errorf(err, "%s: extends", file))
+		}
+		l.stack = append(l.stack, p)
+		base, baseOrigin, err := l.load(p, b)
+		l.stack = l.stack[:len(l.stack)-1]
+		if err != nil {
+			return nil, nil, err
+		}
+		mergeConfig(&merged, origin, base, func(key string) string { return baseOrigin[key] })
+	}
+	mergeConfig(&merged, origin, &c, func(string) string { return file })
+	return &merged, origin, nil
+}
+
+// mergeConfig merges src into dst and records the origin of each merged field.
+//
+// Scalars, lists, auth, backup and when in src replace those in dst if not empty.
+// Env is merged per key, src wins. Profiles are merged per name, src wins.
+func mergeConfig(dst *Config, origin map[string]string, src *Config, srcOrigin func(key string) string) {
+	str := func(key string, d *string, s string) {
+		if s != "" {
+			*d = s
+			origin[key] = srcOrigin(key)
+		}
+	}
+	list := func(key string, d *[]string, s []string) {
+		if len(s) > 0 {
+			*d = slices.Clone(s)
+			origin[key] = srcOrigin(key)
+		}
+	}
+
+	if len(src.URI) > 0 {
+		dst.URI = slices.Clone(src.URI)
+		origin["$.uri"] = srcOrigin("$.uri")
+	}
+	str("$.branch", &dst.Branch, src.Branch)
+	str("$.locald", &dst.LocalDir, src.LocalDir)
+	str("$.lock", &dst.LockFile, src.LockFile)
+	if src.Worktree {
+		dst.Worktree = true
+		origin["$.worktree"] = srcOrigin("$.worktree")
+	}
+	for i, x := range src.Steps.all() {
+		key := "$." + x.name
+		if len(*x.steps) > 0 {
+			*dst.Steps.all()[i].steps = slices.Clone(*x.steps)
+			origin[key] = srcOrigin(key)
+		}
+	}
+	list("$.shell", &dst.Shell, src.Shell)
+	list("$.secrets", &dst.Secrets, src.Secrets)
+	if src.When != nil {
+		w := *src.When
+		dst.When = &w
+		origin["$.when"] = srcOrigin("$.when")
+	}
+	if src.Auth != nil {
+		a := *src.Auth
+		dst.Auth = &a
+		origin["$.auth"] = srcOrigin("$.auth")
+	}
+	if src.Backup != nil {
+		b := *src.Backup
+		dst.Backup = &b
+		origin["$.backup"] = srcOrigin("$.backup")
+	}
+	if len(src.Profiles) > 0 && dst.Profiles == nil {
+		dst.Profiles = map[string]*Profile{}
+	}
+	for _, k := range slices.Sorted(maps.Keys(src.Profiles)) {
+		dst.Profiles[k] = src.Profiles[k]
+		key := "$.profiles." + k
+		origin[key] = srcOrigin(key)
+	}
+	if len(src.Env) > 0 && dst.Env == nil {
+		dst.Env = map[string]string{}
+	}
+	for _, k := range slices.Sorted(maps.Keys(src.Env)) {
+		dst.Env[k] = src.Env[k]
+		key := "$.env." + k
+		origin[key] = srcOrigin(key)
+	}
+}
+
+// applyDefaults fills the empty fields with the default values.
+func applyDefaults(cfg *Config) {
+	d := defaultConfig()
+	if cfg.LockFile == "" {
+		cfg.LockFile = d.LockFile
+	}
+	if cfg.LocalDir == "" {
+		cfg.LocalDir = d.LocalDir
+	}
+}
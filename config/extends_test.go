@@ -0,0 +1,157 @@
+package config_test
+
+import (
+	"berquerant/install-via-git-go/config"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExtends(t *testing.T) {
+	dir := t.TempDir()
+	write := func(t *testing.T, name, content string) string {
+		t.Helper()
+		p := filepath.Join(dir, name)
+		if !assert.Nil(t, os.MkdirAll(filepath.Dir(p), 0755)) {
+			t.FailNow()
+		}
+		if !assert.Nil(t, os.WriteFile(p, []byte(content), 0644)) {
+			t.FailNow()
+		}
+		return p
+	}
+
+	base := write(t, "base/base.yml", `shell: [bash]
+env:
+  A: base
+  B: base
+check:
+  - base check
+setup:
+  - base setup
+branch: develop`)
+	middle := write(t, "middle.yml", `extends: base/base.yml
+env:
+  B: middle
+setup:
+  - middle setup`)
+	write(t, "cycle_a.yml", `extends: cycle_b.yml`)
+	write(t, "cycle_b.yml", `extends: cycle_a.yml`)
+
+	for _, tc := range []struct {
+		title   string
+		input   string
+		want    *config.Config
+		wantErr error
+	}{
+		{
+			title: "merge",
+			input: `extends:
+  - middle.yml
+uri: https://example.com/repo.git
+env:
+  C: main
+install:
+  - main install`,
+			want: &config.Config{
+				URI:      config.URIs{"https://example.com/repo.git"},
+				Branch:   "develop",
+				LocalDir: "repo",
+				LockFile: "lock",
+				Steps: config.Steps{
+					Setup:   []config.Step{{Run: "middle setup"}},
+					Install: []config.Step{{Run: "main install"}},
+					Check:   []config.Step{{Run: "base check"}},
+				},
+				Env: map[string]string{
+					"A": "base",
+					"B": "middle",
+					"C": "main",
+				},
+				Shell: []string{"bash"},
+				Origin: map[string]string{
+					"$.uri":     "main.yml",
+					"$.branch":  base,
+					"$.setup":   middle,
+					"$.install": "main.yml",
+					"$.check":   base,
+					"$.shell":   base,
+					"$.env.A":   base,
+					"$.env.B":   middle,
+					"$.env.C":   "main.yml",
+				},
+			},
+		},
+		{
+			title: "override",
+			input: `extends: base/base.yml
+uri: https://example.com/repo.git
+branch: main
+check:
+  - main check`,
+			want: &config.Config{
+				URI:      config.URIs{"https://example.com/repo.git"},
+				Branch:   "main",
+				LocalDir: "repo",
+				LockFile: "lock",
+				Steps: config.Steps{
+					Setup: []config.Step{{Run: "base setup"}},
+					Check: []config.Step{{Run: "main check"}},
+				},
+				Env: map[string]string{
+					"A": "base",
+					"B": "base",
+				},
+				Shell: []string{"bash"},
+				Origin: map[string]string{
+					"$.uri":    "main.yml",
+					"$.branch": "main.yml",
+					"$.setup":  base,
+					"$.check":  "main.yml",
+					"$.shell":  base,
+					"$.env.A":  base,
+					"$.env.B":  base,
+				},
+			},
+		},
+		{
+			title:   "missing base",
+			input:   `extends: [base/base.yml, missing.yml]`,
+			wantErr: config.ErrParse,
+		},
+		{
+			title:   "cycle",
+			input:   `extends: cycle_a.yml`,
+			wantErr: config.ErrInvalid,
+		},
+		{
+			title: "self",
+			input: `extends: main.yml
+uri: https://example.com/repo.git`,
+			wantErr: config.ErrInvalid,
+		},
+	} {
+		t.Run(tc.title, func(t *testing.T) {
+			got, err := config.Parse(
+				strings.NewReader(tc.input),
+				config.WithPath(filepath.Join(dir, "main.yml")),
+			)
+			if tc.wantErr != nil {
+				assert.ErrorIs(t, err, tc.wantErr)
+				return
+			}
+			if !assert.Nil(t, err) {
+				return
+			}
+			for k, v := range tc.want.Origin {
+				if v == "main.yml" {
+					tc.want.Origin[k] = filepath.Join(dir, "main.yml")
+				}
+			}
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
@@ -0,0 +1,113 @@
+package config
+
+import (
+	"berquerant/install-via-git-go/errorx"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+)
+
+// The formats of the config.
+const (
+	FormatYAML = "yaml"
+	FormatJSON = "json"
+	FormatTOML = "toml"
+)
+
+// Formats are the available formats.
+var Formats = []string{FormatYAML, FormatJSON, FormatTOML}
+
+// tomlLineRegexp matches the first line of TOML, a table header or a key-value pair.
+var tomlLineRegexp = regexp.MustCompile(`^(\[[A-Za-z0-9_."-]+\]|[A-Za-z0-9_."-]+\s*=)`)
+
+// DetectFormat returns the format of the config from the extension of file,
+// or from the content if the extension is unknown.
+func DetectFormat(file string, content []byte) string {
+	switch strings.ToLower(filepath.Ext(file)) {
+	case ".yml", ".yaml":
+		return FormatYAML
+	case ".json":
+		return FormatJSON
+	case ".toml":
+		return FormatTOML
+	}
+	for line := range strings.Lines(string(content)) {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		switch {
+		case strings.HasPrefix(line, "{"):
+			return FormatJSON
+		case tomlLineRegexp.MatchString(line):
+			return FormatTOML
+		default:
+			return FormatYAML
+		}
+	}
+	return FormatYAML
+}
+
+// toYAML converts the content into YAML.
+// Returns false if the content is re-encoded, then the positions in the result are not of the content.
+//
+// JSON is also YAML so converted only if it has steps, the steps are moved to the top level
+// as the output of parse.
+func toYAML(format string, content []byte) ([]byte, bool, error) {
+	var v map[string]any
+	switch format {
+	case FormatYAML:
+		return content, true, nil
+	case FormatJSON:
+		if err := json.Unmarshal(content, &v); err != nil {
+			return nil, false, errors.Join(ErrParse, err)
+		}
+		if _, ok := v["steps"]; !ok {
+			return content, true, nil
+		}
+	case FormatTOML:
+		if err := toml.Unmarshal(content, &v); err != nil {
+			return nil, false, errors.Join(ErrParse, err)
+		}
+	default:
+		return nil, false, errorx.Errorf(ErrParse, "unknown format %s", format)
+	}
+	if steps, ok := v["steps"].(map[string]any); ok {
+		delete(v, "steps")
+		for k, x := range steps {
+			if _, ok := v[k]; ok {
+				return nil, false, errorx.Errorf(ErrInvalid, "%s in both steps and top level", k)
+			}
+			v[k] = x
+		}
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, false, errors.Join(ErrParse, err)
+	}
+	return b, false, nil
+}
+
+// MarshalTOML returns the TOML encoding of v, by the json tags.
+func MarshalTOML(v any) ([]byte, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var m map[string]any
+	if err := json.Unmarshal(b, &m); err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	enc := toml.NewEncoder(&buf)
+	enc.SetIndentTables(true)
+	if err := enc.Encode(m); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,149 @@
+package config_test
+
+import (
+	"berquerant/install-via-git-go/config"
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDetectFormat(t *testing.T) {
+	for _, tc := range []struct {
+		title   string
+		file    string
+		content string
+		want    string
+	}{
+		{title: "yml", file: "ivg.yml", want: config.FormatYAML},
+		{title: "yaml", file: "ivg.YAML", want: config.FormatYAML},
+		{title: "json", file: "ivg.json", want: config.FormatJSON},
+		{title: "toml", file: "ivg.toml", want: config.FormatTOML},
+		{title: "yaml content", file: "-", content: "# comment\nuri: x", want: config.FormatYAML},
+		{title: "json content", file: "-", content: "\n{\"uri\": \"x\"}", want: config.FormatJSON},
+		{title: "toml content", file: "-", content: "# comment\nuri = \"x\"", want: config.FormatTOML},
+		{title: "toml table", file: "ivg.conf", content: "[env]\nA = \"a\"", want: config.FormatTOML},
+		{title: "empty", file: "-", want: config.FormatYAML},
+	} {
+		t.Run(tc.title, func(t *testing.T) {
+			assert.Equal(t, tc.want, config.DetectFormat(tc.file, []byte(tc.content)))
+		})
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	want := &config.Config{
+		URI:      config.URIs{"https://example.com/repo.git"},
+		LocalDir: "repo",
+		LockFile: "lock",
+		Steps: config.Steps{
+			Install: []config.Step{{Run: "make"}},
+		},
+		Env: map[string]string{
+			"A": "a",
+		},
+		Origin: map[string]string{
+			"$.uri":     "-",
+			"$.install": "-",
+			"$.env.A":   "-",
+		},
+	}
+
+	for _, tc := range []struct {
+		title   string
+		input   string
+		format  string
+		wantErr error
+	}{
+		{
+			title: "json",
+			input: `{"uri": "https://example.com/repo.git", "install": ["make"], "env": {"A": "a"}}`,
+		},
+		{
+			title: "json steps",
+			input: `{"uri": "https://example.com/repo.git", "steps": {"install": ["make"]}, "env": {"A": "a"}}`,
+		},
+		{
+			title: "toml",
+			input: `uri = "https://example.com/repo.git"
+install = ["make"]
+
+[env]
+A = "a"`,
+		},
+		{
+			title:  "toml by format",
+			format: config.FormatTOML,
+			input: `uri = "https://example.com/repo.git"
+[steps]
+install = ["make"]
+[env]
+A = "a"`,
+		},
+		{
+			title: "toml unknown key",
+			input: `uri = "https://example.com/repo.git"
+instal = ["make"]`,
+			wantErr: config.ErrParse,
+		},
+		{
+			title:   "steps conflict",
+			input:   `{"uri": "x", "install": ["a"], "steps": {"install": ["b"]}}`,
+			wantErr: config.ErrInvalid,
+		},
+		{
+			title:   "unknown format",
+			format:  "ini",
+			input:   `uri = x`,
+			wantErr: config.ErrParse,
+		},
+	} {
+		t.Run(tc.title, func(t *testing.T) {
+			got, err := config.Parse(strings.NewReader(tc.input), config.WithFormat(tc.format))
+			if tc.wantErr != nil {
+				assert.ErrorIs(t, err, tc.wantErr)
+				return
+			}
+			if !assert.Nil(t, err) {
+				return
+			}
+			assert.Equal(t, want, got)
+		})
+	}
+}
+
+func TestMarshalTOML(t *testing.T) {
+	cfg, err := config.Parse(strings.NewReader(`uri:
+  - https://example.com/repo.git
+  - https://mirror.example.com/repo.git
+install:
+  - make
+env:
+  A: a`))
+	if !assert.Nil(t, err) {
+		return
+	}
+	b, err := config.MarshalTOML(cfg)
+	if !assert.Nil(t, err) {
+		return
+	}
+	got, err := config.Parse(bytes.NewReader(b), config.WithFormat(config.FormatTOML))
+	if !assert.Nil(t, err, string(b)) {
+		return
+	}
+	assert.Equal(t, cfg.URI, got.URI)
+	assert.Equal(t, cfg.Steps, got.Steps)
+	assert.Equal(t, cfg.Env, got.Env)
+
+	j, err := json.Marshal(cfg)
+	if !assert.Nil(t, err) {
+		return
+	}
+	got, err = config.Parse(bytes.NewReader(j))
+	if !assert.Nil(t, err, string(j)) {
+		return
+	}
+	assert.Equal(t, cfg.Steps, got.Steps)
+}
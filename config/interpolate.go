@@ -0,0 +1,75 @@
+package config
+
+import (
+	"berquerant/install-via-git-go/errorx"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// varRegexp matches ${NAME} and the escaped $${NAME}.
+var varRegexp = regexp.MustCompile(`\$?\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+type interpolator struct {
+	lookup    func(name string) (string, bool)
+	undefined []string
+}
+
+// expand replaces ${NAME} with the value of NAME and $${NAME} with ${NAME}.
+// Undefined ${NAME} is left as is and recorded.
+func (x *interpolator) expand(s string) string {
+	return varRegexp.ReplaceAllStringFunc(s, func(m string) string {
+		if strings.HasPrefix(m, "$$") {
+			return m[1:]
+		}
+		name := m[2 : len(m)-1]
+		if v, ok := x.lookup(name); ok {
+			return v
+		}
+		if !slices.Contains(x.undefined, name) {
+			x.undefined = append(x.undefined, name)
+		}
+		return m
+	})
+}
+
+func (x *interpolator) expandAll(ss []string) {
+	for i, s := range ss {
+		ss[i] = x.expand(s)
+	}
+}
+
+// interpolate expands ${NAME} in uri, branch, locald, lock and backup.dir.
+//
+// NAME is looked up in vars, env and the process environment in order.
+// Steps are left as is, the shell expands ${NAME} from their environment
+// that has vars, env, IVG_URI, IVG_BRANCH, IVG_LOCALD, IVG_LOCK, IVG_OS and IVG_ARCH,
+// not to break the quoting of the scripts with the values.
+// If strict, undefined NAME is an error.
+func interpolate(cfg *Config, vars map[string]string, strict bool) error {
+	x := &interpolator{
+		lookup: func(name string) (string, bool) {
+			if v, ok := vars[name]; ok {
+				return v, true
+			}
+			if v, ok := cfg.Env[name]; ok {
+				return v, true
+			}
+			return os.LookupEnv(name)
+		},
+	}
+
+	x.expandAll(cfg.URI)
+	cfg.Branch = x.expand(cfg.Branch)
+	cfg.LocalDir = x.expand(cfg.LocalDir)
+	cfg.LockFile = x.expand(cfg.LockFile)
+	if cfg.Backup != nil {
+		cfg.Backup.Dir = x.expand(cfg.Backup.Dir)
+	}
+
+	if strict && len(x.undefined) > 0 {
+		return errorx.Errorf(ErrInvalid, "undefined variables: %s", strings.Join(x.undefined, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package config_test
+
+import (
+	"berquerant/install-via-git-go/config"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseInterpolation(t *testing.T) {
+	t.Setenv("IVG_TEST_HOST", "example.com")
+	t.Setenv("IVG_TEST_PATH", "/usr/bin")
+
+	const input = `uri: https://${IVG_TEST_HOST}/${NAME}.git
+branch: v${VERSION}
+locald: ${NAME}-${VERSION}
+lock: ${IVG_WORKD}/lock
+env:
+  NAME: tool
+  VERSION: "1.0"
+  MSG: say "hi" $(whoami)
+install:
+  - echo ${IVG_URI} ${IVG_BRANCH} ${IVG_LOCALD}
+  - echo "${MSG}" ${UNDEFINED}
+  - for f in a b; do echo ${f} ${IVG_TEST_PATH}; done`
+
+	t.Run("expand", func(t *testing.T) {
+		got, err := config.Parse(strings.NewReader(input), config.WithVars(map[string]string{
+			"IVG_WORKD": "/work",
+		}))
+		if !assert.Nil(t, err) {
+			return
+		}
+		assert.Equal(t, config.URIs{"https://example.com/tool.git"}, got.URI)
+		assert.Equal(t, "v1.0", got.Branch)
+		assert.Equal(t, "tool-1.0", got.LocalDir)
+		assert.Equal(t, "/work/lock", got.LockFile)
+		// steps are expanded by the shell
+		assert.Equal(t, []config.Step{
+			{Run: "echo ${IVG_URI} ${IVG_BRANCH} ${IVG_LOCALD}"},
+			{Run: `echo "${MSG}" ${UNDEFINED}`},
+			{Run: "for f in a b; do echo ${f} ${IVG_TEST_PATH}; done"},
+		}, got.Steps.Install)
+	})
+
+	t.Run("strict steps", func(t *testing.T) {
+		_, err := config.Parse(strings.NewReader(input), config.WithStrict(true), config.WithVars(map[string]string{
+			"IVG_WORKD": "/work",
+		}))
+		assert.Nil(t, err)
+	})
+
+	t.Run("strict", func(t *testing.T) {
+		_, err := config.Parse(strings.NewReader(input), config.WithStrict(true))
+		assert.ErrorIs(t, err, config.ErrInvalid)
+		assert.ErrorContains(t, err, "IVG_WORKD")
+	})
+
+	t.Run("escape", func(t *testing.T) {
+		got, err := config.Parse(strings.NewReader(`uri: https://example.com/$${NAME}.git`))
+		if !assert.Nil(t, err) {
+			return
+		}
+		assert.Equal(t, config.URIs{"https://example.com/${NAME}.git"}, got.URI)
+	})
+}
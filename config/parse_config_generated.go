@@ -0,0 +1,123 @@
+// Code generated by "goconfig -field Strict bool|Vars map[string]string|Path string|Format string|Profile string|DefaultProfile string -prefix Parse -option -output parse_config_generated.go"; DO NOT EDIT.
+
+package config
+
+type ParseConfigItem[T any] struct {
+	modified     bool
+	value        T
+	defaultValue T
+}
+
+func (s *ParseConfigItem[T]) Set(value T) {
+	s.modified = true
+	s.value = value
+}
+func (s *ParseConfigItem[T]) Get() T {
+	if s.modified {
+		return s.value
+	}
+	return s.defaultValue
+}
+func (s *ParseConfigItem[T]) Default() T {
+	return s.defaultValue
+}
+func (s *ParseConfigItem[T]) IsModified() bool {
+	return s.modified
+}
+func NewParseConfigItem[T any](defaultValue T) *ParseConfigItem[T] {
+	return &ParseConfigItem[T]{
+		defaultValue: defaultValue,
+	}
+}
+
+type ParseConfig struct {
+	Strict         *ParseConfigItem[bool]
+	Vars           *ParseConfigItem[map[string]string]
+	Path           *ParseConfigItem[string]
+	Format         *ParseConfigItem[string]
+	Profile        *ParseConfigItem[string]
+	DefaultProfile *ParseConfigItem[string]
+}
+type ParseConfigBuilder struct {
+	strict         bool
+	vars           map[string]string
+	path           string
+	format         string
+	profile        string
+	defaultProfile string
+}
+
+func (s *ParseConfigBuilder) Strict(v bool) *ParseConfigBuilder {
+	s.strict = v
+	return s
+}
+func (s *ParseConfigBuilder) Vars(v map[string]string) *ParseConfigBuilder {
+	s.vars = v
+	return s
+}
+func (s *ParseConfigBuilder) Path(v string) *ParseConfigBuilder {
+	s.path = v
+	return s
+}
+func (s *ParseConfigBuilder) Format(v string) *ParseConfigBuilder {
+	s.format = v
+	return s
+}
+func (s *ParseConfigBuilder) Profile(v string) *ParseConfigBuilder {
+	s.profile = v
+	return s
+}
+func (s *ParseConfigBuilder) DefaultProfile(v string) *ParseConfigBuilder {
+	s.defaultProfile = v
+	return s
+}
+func (s *ParseConfigBuilder) Build() *ParseConfig {
+	return &ParseConfig{
+		Strict:         NewParseConfigItem(s.strict),
+		Vars:           NewParseConfigItem(s.vars),
+		Path:           NewParseConfigItem(s.path),
+		Format:         NewParseConfigItem(s.format),
+		Profile:        NewParseConfigItem(s.profile),
+		DefaultProfile: NewParseConfigItem(s.defaultProfile),
+	}
+}
+
+func NewParseConfigBuilder() *ParseConfigBuilder { return &ParseConfigBuilder{} }
+func (s *ParseConfig) Apply(opt ...ParseConfigOption) {
+	for _, x := range opt {
+		x(s)
+	}
+}
+
+type ParseConfigOption func(*ParseConfig)
+
+func WithStrict(v bool) ParseConfigOption {
+	return func(c *ParseConfig) {
+		c.Strict.Set(v)
+	}
+}
+func WithVars(v map[string]string) ParseConfigOption {
+	return func(c *ParseConfig) {
+		c.Vars.Set(v)
+	}
+}
+func WithPath(v string) ParseConfigOption {
+	return func(c *ParseConfig) {
+		c.Path.Set(v)
+	}
+}
+func WithFormat(v string) ParseConfigOption {
+	return func(c *ParseConfig) {
+		c.Format.Set(v)
+	}
+}
+func WithProfile(v string) ParseConfigOption {
+	return func(c *ParseConfig) {
+		c.Profile.Set(v)
+	}
+}
+func WithDefaultProfile(v string) ParseConfigOption {
+	return func(c *ParseConfig) {
+		c.DefaultProfile.Set(v)
+	}
+}
@@ -0,0 +1,150 @@
+package config_test
+
+import (
+	"berquerant/install-via-git-go/config"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProfile(t *testing.T) {
+	const input = `uri: https://example.com/repo.git
+env:
+  A: base
+  B: base
+install:
+  - make
+  - make docs
+profiles:
+  ci:
+    branch: develop
+    env:
+      B: ci
+      C: ci
+    install:
+      - make
+  empty: {}`
+
+	for _, tc := range []struct {
+		title          string
+		profile        string
+		defaultProfile string
+		want           *config.Config
+		wantErr        error
+	}{
+		{
+			title: "no profile",
+			want: &config.Config{
+				Steps: config.Steps{
+					Install: []config.Step{{Run: "make"}, {Run: "make docs"}},
+				},
+				Env: map[string]string{"A": "base", "B": "base"},
+			},
+		},
+		{
+			title:   "ci",
+			profile: "ci",
+			want: &config.Config{
+				Branch: "develop",
+				Steps: config.Steps{
+					Install: []config.Step{{Run: "make"}},
+				},
+				Env:     map[string]string{"A": "base", "B": "ci", "C": "ci"},
+				Profile: "ci",
+			},
+		},
+		{
+			title:   "empty",
+			profile: "empty",
+			want: &config.Config{
+				Steps: config.Steps{
+					Install: []config.Step{{Run: "make"}, {Run: "make docs"}},
+				},
+				Env:     map[string]string{"A": "base", "B": "base"},
+				Profile: "empty",
+			},
+		},
+		{
+			title:   "unknown",
+			profile: "dev",
+			wantErr: config.ErrInvalid,
+		},
+		{
+			title:          "default",
+			defaultProfile: "ci",
+			want: &config.Config{
+				Branch: "develop",
+				Steps: config.Steps{
+					Install: []config.Step{{Run: "make"}},
+				},
+				Env:     map[string]string{"A": "base", "B": "ci", "C": "ci"},
+				Profile: "ci",
+			},
+		},
+		{
+			title:          "override default",
+			profile:        "empty",
+			defaultProfile: "ci",
+			want: &config.Config{
+				Steps: config.Steps{
+					Install: []config.Step{{Run: "make"}, {Run: "make docs"}},
+				},
+				Env:     map[string]string{"A": "base", "B": "base"},
+				Profile: "empty",
+			},
+		},
+		{
+			title:          "unknown default",
+			defaultProfile: "dev",
+			want: &config.Config{
+				Steps: config.Steps{
+					Install: []config.Step{{Run: "make"}, {Run: "make docs"}},
+				},
+				Env: map[string]string{"A": "base", "B": "base"},
+			},
+		},
+		{
+			title:          "unknown with default",
+			profile:        "dev",
+			defaultProfile: "ci",
+			wantErr:        config.ErrInvalid,
+		},
+	} {
+		t.Run(tc.title, func(t *testing.T) {
+			got, err := config.Parse(strings.NewReader(input),
+				config.WithProfile(tc.profile),
+				config.WithDefaultProfile(tc.defaultProfile),
+			)
+			if tc.wantErr != nil {
+				assert.ErrorIs(t, err, tc.wantErr)
+				return
+			}
+			if !assert.Nil(t, err) {
+				return
+			}
+			assert.Equal(t, tc.want.Branch, got.Branch)
+			assert.Equal(t, tc.want.Steps, got.Steps)
+			assert.Equal(t, tc.want.Env, got.Env)
+			assert.Equal(t, tc.want.Profile, got.Profile)
+		})
+	}
+
+	t.Run("origin", func(t *testing.T) {
+		got, err := config.Parse(strings.NewReader(input), config.WithProfile("ci"))
+		if !assert.Nil(t, err) {
+			return
+		}
+		assert.Equal(t, "- (profile ci)", got.Origin["$.branch"])
+		assert.Equal(t, "- (profile ci)", got.Origin["$.env.B"])
+		assert.Equal(t, "-", got.Origin["$.env.A"])
+	})
+
+	t.Run("unknown key", func(t *testing.T) {
+		_, err := config.Parse(strings.NewReader(`uri: https://example.com/repo.git
+profiles:
+  ci:
+    brnch: develop`))
+		assert.ErrorContains(t, err, "-:4:5: unknown key $.profiles.ci.brnch")
+	})
+}
@@ -0,0 +1,124 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+)
+
+// Schema is a JSON Schema.
+type Schema struct {
+	Schema               string             `json:"$schema,omitempty"`
+	Title                string             `json:"title,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	AdditionalProperties any                `json:"additionalProperties,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	OneOf                []*Schema          `json:"oneOf,omitempty"`
+}
+
+// alternative returns the schema of oneOf with the type, or itself.
+func (s *Schema) alternative(typ string) *Schema {
+	for _, x := range s.OneOf {
+		if x.Type == typ {
+			return x
+		}
+	}
+	return s
+}
+
+// property returns the schema of the key of the object.
+func (s *Schema) property(key string) (*Schema, bool) {
+	if s.Type != "object" {
+		return nil, false
+	}
+	if p, ok := s.Properties[key]; ok {
+		return p, true
+	}
+	if p, ok := s.AdditionalProperties.(*Schema); ok {
+		return p, true
+	}
+	return nil, false
+}
+
+// strict returns true if the object rejects the unknown keys.
+func (s *Schema) strict() bool {
+	b, ok := s.AdditionalProperties.(bool)
+	return s.Type == "object" && ok && !b
+}
+
+// NewSchema returns the JSON Schema of Config, generated from the yaml tags.
+func NewSchema() *Schema {
+	s := schemaOf(reflect.TypeFor[Config]())
+	s.Schema = "https://json-schema.org/draft/2020-12/schema"
+	s.Title = "install-via-git config"
+	return s
+}
+
+func schemaOf(t reflect.Type) *Schema {
+	switch t {
+	case reflect.TypeFor[URIs](), reflect.TypeFor[Strings]():
+		return &Schema{
+			OneOf: []*Schema{
+				{Type: "string"},
+				{Type: "array", Items: &Schema{Type: "string"}},
+			},
+		}
+	case reflect.TypeFor[Step]():
+		return &Schema{
+			OneOf: []*Schema{
+				{Type: "string"},
+				schemaOf(reflect.TypeFor[stepObject]()),
+			},
+		}
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		return schemaOf(t.Elem())
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.Slice:
+		return &Schema{Type: "array", Items: schemaOf(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: schemaOf(t.Elem())}
+	case reflect.Struct:
+		s := &Schema{
+			Type:                 "object",
+			Properties:           map[string]*Schema{},
+			AdditionalProperties: false,
+		}
+		addProperties(s, t)
+		return s
+	default:
+		// empty schema accepts any value
+		return &Schema{}
+	}
+}
+
+func addProperties(s *Schema, t reflect.Type) {
+	for i := range t.NumField() {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		name, opts, _ := strings.Cut(f.Tag.Get("yaml"), ",")
+		if name == "-" {
+			continue
+		}
+		if strings.Contains(opts, "inline") {
+			addProperties(s, f.Type)
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(f.Name)
+		}
+		s.Properties[name] = schemaOf(f.Type)
+	}
+}
@@ -0,0 +1,98 @@
+package config
+
+import (
+	"berquerant/install-via-git-go/errorx"
+	"bufio"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+//go:generate go tool stringer -type=SourceKind -output sourcekind_stringer_generated.go
+
+type SourceKind int
+
+const (
+	// SKremote means that the uri is a remote repository, e.g. https, ssh.
+	SKremote SourceKind = iota
+	// SKfile means that the uri is a file:// url.
+	SKfile
+	// SKlocal means that the uri is a local repository directory.
+	SKlocal
+	// SKbundle means that the uri is a git bundle file.
+	SKbundle
+)
+
+// isLocalPath returns true if uri should be treated as a local path by git clone.
+//
+// As git does, uri is a scp-like remote, e.g. host:path, user@host:path,
+// when a colon appears before the first slash.
+func isLocalPath(uri string) bool {
+	if strings.Contains(uri, "://") {
+		return false
+	}
+	i := strings.Index(uri, ":")
+	return i <= 0 || strings.Contains(uri[:i], "/")
+}
+
+// resolveSource returns uri with the relative local path resolved from dir, without accessing the filesystem.
+func resolveSource(uri, dir string) string {
+	if !isLocalPath(uri) || filepath.IsAbs(uri) {
+		return uri
+	}
+	if dir == "" {
+		if abs, err := filepath.Abs(uri); err == nil {
+			return abs
+		}
+		return uri
+	}
+	return filepath.Join(dir, uri)
+}
+
+// DetectSource returns the kind of uri and normalized uri.
+// Local paths are converted to absolute paths and must exist.
+func DetectSource(uri string) (SourceKind, string, error) {
+	if strings.HasPrefix(uri, "file://") {
+		u, err := url.Parse(uri)
+		if err != nil {
+			return SKfile, "", errorx.Errorf(ErrInvalid, "uri %s: %v", uri, err)
+		}
+		if _, err := os.Stat(u.Path); err != nil {
+			return SKfile, "", errorx.Errorf(ErrInvalid, "uri %s: %v", uri, err)
+		}
+		return SKfile, uri, nil
+	}
+	if !isLocalPath(uri) {
+		return SKremote, uri, nil
+	}
+
+	path, err := filepath.Abs(uri)
+	if err != nil {
+		return SKlocal, "", errorx.Errorf(ErrInvalid, "uri %s: %v", uri, err)
+	}
+	stat, err := os.Stat(path)
+	if err != nil {
+		return SKlocal, "", errorx.Errorf(ErrInvalid, "uri %s: %v", uri, err)
+	}
+	if stat.IsDir() {
+		return SKlocal, path, nil
+	}
+	if !isBundle(path) {
+		return SKbundle, "", errorx.Errorf(ErrInvalid, "uri %s: not a git bundle", uri)
+	}
+	return SKbundle, path, nil
+}
+
+func isBundle(path string) bool {
+	f, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	header, err := bufio.NewReader(f).ReadString('\n')
+	if err != nil {
+		return false
+	}
+	return strings.HasPrefix(header, "# v") && strings.HasSuffix(header, " git bundle\n")
+}
@@ -0,0 +1,186 @@
+package config_test
+
+import (
+	"berquerant/install-via-git-go/config"
+	"berquerant/install-via-git-go/execx"
+	"berquerant/install-via-git-go/filepathx"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDetectSource(t *testing.T) {
+	base, err := filepathx.NewPath(t.TempDir())
+	if !assert.Nil(t, err) {
+		return
+	}
+	baseDir := base.String()
+	repoDir := filepath.Join(baseDir, "repo")
+	bundleFile := filepath.Join(baseDir, "repo.bundle")
+	textFile := filepath.Join(baseDir, "text")
+	env := execx.EnvFromSlice([]string{
+		"GIT_AUTHOR_NAME=ivg",
+		"GIT_AUTHOR_EMAIL=ivg@example.com",
+		"GIT_COMMITTER_NAME=ivg",
+		"GIT_COMMITTER_EMAIL=ivg@example.com",
+	})
+	_, err = execx.NewExecutorFromStrings([]string{
+		"git init -q repo",
+		"git -C repo commit -q --allow-empty -m init",
+		"git -C repo bundle create ../repo.bundle --all",
+	}, "bash").Execute(context.TODO(), execx.WithDir(base.DirPath()), execx.WithEnv(env))
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Nil(t, os.WriteFile(textFile, []byte("text"), 0600))
+
+	for _, tc := range []struct {
+		title   string
+		uri     string
+		kind    config.SourceKind
+		want    string
+		wantErr bool
+	}{
+		{
+			title: "https",
+			uri:   "https://github.com/berquerant/install-via-git-go.git",
+			kind:  config.SKremote,
+			want:  "https://github.com/berquerant/install-via-git-go.git",
+		},
+		{
+			title: "scp-like",
+			uri:   "git@github.com:berquerant/install-via-git-go.git",
+			kind:  config.SKremote,
+			want:  "git@github.com:berquerant/install-via-git-go.git",
+		},
+		{
+			title: "scp-like without user",
+			uri:   "github.com:berquerant/install-via-git-go.git",
+			kind:  config.SKremote,
+			want:  "github.com:berquerant/install-via-git-go.git",
+		},
+		{
+			title:   "local path with colon after slash",
+			uri:     filepath.Join(baseDir, "not:found"),
+			kind:    config.SKlocal,
+			wantErr: true,
+		},
+		{
+			title: "file url",
+			uri:   "file://" + repoDir,
+			kind:  config.SKfile,
+			want:  "file://" + repoDir,
+		},
+		{
+			title:   "file url not found",
+			uri:     "file://" + filepath.Join(baseDir, "notfound"),
+			kind:    config.SKfile,
+			wantErr: true,
+		},
+		{
+			title: "local dir",
+			uri:   repoDir,
+			kind:  config.SKlocal,
+			want:  repoDir,
+		},
+		{
+			title:   "local dir not found",
+			uri:     filepath.Join(baseDir, "notfound"),
+			kind:    config.SKlocal,
+			wantErr: true,
+		},
+		{
+			title: "bundle",
+			uri:   bundleFile,
+			kind:  config.SKbundle,
+			want:  bundleFile,
+		},
+		{
+			title:   "not bundle",
+			uri:     textFile,
+			kind:    config.SKbundle,
+			wantErr: true,
+		},
+	} {
+		t.Run(tc.title, func(t *testing.T) {
+			kind, got, err := config.DetectSource(tc.uri)
+			assert.Equal(t, tc.kind, kind)
+			if tc.wantErr {
+				assert.ErrorIs(t, err, config.ErrInvalid)
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestParseLocalSource(t *testing.T) {
+	dir := t.TempDir()
+	write := func(t *testing.T, name, content string) string {
+		t.Helper()
+		p := filepath.Join(dir, name)
+		if !assert.Nil(t, os.MkdirAll(filepath.Dir(p), 0755)) {
+			t.FailNow()
+		}
+		if !assert.Nil(t, os.WriteFile(p, []byte(content), 0644)) {
+			t.FailNow()
+		}
+		return p
+	}
+	write(t, "base/base.yml", `uri: ../repos/base`)
+	write(t, "profile/profile.yml", `profiles:
+  local:
+    uri: ./repo`)
+
+	for _, tc := range []struct {
+		title   string
+		path    string
+		content string
+		profile string
+		want    []string
+	}{
+		{
+			title:   "relative to config",
+			path:    "sub/ivg.yml",
+			content: `uri: [../repo, /abs/repo, https://example.com/repo.git]`,
+			want: []string{
+				filepath.Join(dir, "repo"),
+				"/abs/repo",
+				"https://example.com/repo.git",
+			},
+		},
+		{
+			title:   "relative to base config",
+			path:    "ivg.yml",
+			content: `extends: base/base.yml`,
+			want:    []string{filepath.Join(dir, "repos/base")},
+		},
+		{
+			title: "relative to profile config",
+			path:  "ivg.yml",
+			content: `extends: profile/profile.yml
+uri: repo`,
+			profile: "local",
+			want:    []string{filepath.Join(dir, "profile/repo")},
+		},
+	} {
+		t.Run(tc.title, func(t *testing.T) {
+			p := write(t, tc.path, tc.content)
+			f, err := os.Open(p)
+			if !assert.Nil(t, err) {
+				return
+			}
+			defer f.Close()
+			// the sources do not exist, not checked when parsing
+			got, err := config.Parse(f, config.WithPath(p), config.WithProfile(tc.profile))
+			if !assert.Nil(t, err) {
+				return
+			}
+			assert.Equal(t, tc.want, []string(got.URI))
+		})
+	}
+}
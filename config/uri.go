@@ -0,0 +1,104 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+)
+
+// URIs is a list of repository uris, the primary and the fallback mirrors.
+// It is written as a string or a list of strings.
+type URIs []string
+
+// Primary returns the first uri.
+func (u URIs) Primary() string {
+	if len(u) == 0 {
+		return ""
+	}
+	return u[0]
+}
+
+// Fallbacks returns the uris except the primary.
+func (u URIs) Fallbacks() []string {
+	if len(u) < 2 {
+		return nil
+	}
+	return u[1:]
+}
+
+func (u *URIs) UnmarshalYAML(unmarshal func(any) error) error {
+	ss, err := unmarshalStringOrList(unmarshal)
+	if err != nil {
+		return err
+	}
+	*u = URIs(ss)
+	return nil
+}
+
+func (u URIs) MarshalYAML() (any, error) {
+	return marshalStringOrList(u), nil
+}
+
+func (u *URIs) UnmarshalJSON(b []byte) error {
+	ss, err := unmarshalStringOrList(func(v any) error {
+		return json.Unmarshal(b, v)
+	})
+	if err != nil {
+		return err
+	}
+	*u = URIs(ss)
+	return nil
+}
+
+func (u URIs) MarshalJSON() ([]byte, error) {
+	return json.Marshal(marshalStringOrList(u))
+}
+
+// Strings is written as a string or a list of strings.
+type Strings []string
+
+func (f *Strings) UnmarshalYAML(unmarshal func(any) error) error {
+	ss, err := unmarshalStringOrList(unmarshal)
+	if err != nil {
+		return err
+	}
+	*f = Strings(ss)
+	return nil
+}
+
+func (f Strings) MarshalYAML() (any, error) {
+	return marshalStringOrList(f), nil
+}
+
+func (f *Strings) UnmarshalJSON(b []byte) error {
+	ss, err := unmarshalStringOrList(func(v any) error {
+		return json.Unmarshal(b, v)
+	})
+	if err != nil {
+		return err
+	}
+	*f = Strings(ss)
+	return nil
+}
+
+func (f Strings) MarshalJSON() ([]byte, error) {
+	return json.Marshal(marshalStringOrList(f))
+}
+
+func unmarshalStringOrList(unmarshal func(any) error) ([]string, error) {
+	var s string
+	if err := unmarshal(&s); err == nil {
+		return []string{s}, nil
+	}
+	var ss []string
+	if err := unmarshal(&ss); err != nil {
+		return nil, errors.Join(ErrParse, err)
+	}
+	return ss, nil
+}
+
+func marshalStringOrList(ss []string) any {
+	if len(ss) == 1 {
+		return ss[0]
+	}
+	return ss
+}
@@ -0,0 +1,113 @@
+package config
+
+import (
+	"berquerant/install-via-git-go/errorx"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/goccy/go-yaml/ast"
+	"github.com/goccy/go-yaml/parser"
+)
+
+// unknownKeys returns the errors of the keys not in the schema, with the positions if positional.
+func unknownKeys(file string, bytes []byte, positional bool) ([]error, error) {
+	f, err := parser.ParseBytes(bytes, 0)
+	if err != nil {
+		return nil, errors.Join(ErrParse, errorx.Errorf(err, "%s", file))
+	}
+	var (
+		schema = NewSchema()
+		errs   []error
+	)
+	var walk func(path string, node ast.Node, s *Schema)
+	walk = func(path string, node ast.Node, s *Schema) {
+		switch n := node.(type) {
+		case *ast.AnchorNode:
+			walk(path, n.Value, s)
+		case *ast.TagNode:
+			walk(path, n.Value, s)
+		case *ast.SequenceNode:
+			s = s.alternative("array")
+			if s.Items == nil {
+				return
+			}
+			for i, v := range n.Values {
+				walk(fmt.Sprintf("%s[%d]", path, i), v, s.Items)
+			}
+		case *ast.MappingNode:
+			s = s.alternative("object")
+			for _, v := range n.Values {
+				walk(path, v, s)
+			}
+		case *ast.MappingValueNode:
+			s = s.alternative("object")
+			if n.Key.IsMergeKey() {
+				return
+			}
+			key := n.Key.String()
+			if k, ok := n.Key.(ast.ScalarNode); ok {
+				key = fmt.Sprint(k.GetValue())
+			}
+			p, ok := s.property(key)
+			if !ok {
+				if !s.strict() {
+					return
+				}
+				if positional {
+					pos := n.Key.GetToken().Position
+					errs = append(errs, errorx.Errorf(ErrParse, "%s:%d:%d: unknown key %s.%s",
+						file, pos.Line, pos.Column, path, key))
+				} else {
+					errs = append(errs, errorx.Errorf(ErrParse, "%s: unknown key %s.%s", file, path, key))
+				}
+				return
+			}
+			walk(path+"."+key, n.Value, p)
+		}
+	}
+	for _, doc := range f.Docs {
+		if doc.Body != nil {
+			walk("$", doc.Body, schema)
+		}
+	}
+	return errs, nil
+}
+
+// Validate parses the config and returns all the problems, nil if valid.
+//
+// In addition to Parse, Validate checks the shell exists,
+// locald and lock are in workDir and the steps are not empty.
+func Validate(r io.Reader, opt ...ParseConfigOption) []error {
+	cfg, errs, err := parse(r, opt...)
+	if err != nil {
+		return []error{err}
+	}
+	return append(errs, check(cfg)...)
+}
+
+func check(cfg *Config) []error {
+	var errs []error
+	if len(cfg.Shell) > 0 {
+		if _, err := exec.LookPath(cfg.Shell[0]); err != nil {
+			errs = append(errs, errorx.Errorf(ErrInvalid, "shell: %v", err))
+		}
+	}
+	if !filepath.IsLocal(cfg.LocalDir) {
+		errs = append(errs, errorx.Errorf(ErrInvalid, "locald: %s is not in workDir", cfg.LocalDir))
+	}
+	if !filepath.IsLocal(cfg.LockFile) {
+		errs = append(errs, errorx.Errorf(ErrInvalid, "lock: %s is not in workDir", cfg.LockFile))
+	}
+	for _, x := range cfg.Steps.all() {
+		for i, s := range *x.steps {
+			if strings.TrimSpace(s.Run) == "" {
+				errs = append(errs, errorx.Errorf(ErrInvalid, "%s[%d]: empty step", x.name, i))
+			}
+		}
+	}
+	return errs
+}
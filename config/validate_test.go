@@ -0,0 +1,134 @@
+package config_test
+
+import (
+	"berquerant/install-via-git-go/config"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUnknownKeys(t *testing.T) {
+	for _, tc := range []struct {
+		title string
+		input string
+		want  []string
+	}{
+		{
+			title: "valid",
+			input: `uri: https://example.com/repo.git
+env:
+  ANY_KEY: value
+auth:
+  tokenEnv: TOKEN
+install:
+  - make`,
+		},
+		{
+			title: "unknown keys",
+			input: `uri: https://example.com/repo.git
+instal:
+  - make
+auth:
+  token: xxx`,
+			want: []string{
+				"-:2:1: unknown key $.instal",
+				"-:5:3: unknown key $.auth.token",
+			},
+		},
+	} {
+		t.Run(tc.title, func(t *testing.T) {
+			_, err := config.Parse(strings.NewReader(tc.input))
+			if len(tc.want) == 0 {
+				assert.Nil(t, err)
+				return
+			}
+			assert.ErrorIs(t, err, config.ErrParse)
+			for _, w := range tc.want {
+				assert.Contains(t, err.Error(), w)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	for _, tc := range []struct {
+		title string
+		input string
+		want  []string
+	}{
+		{
+			title: "valid",
+			input: `uri: https://example.com/repo.git
+shell: [sh]
+install:
+  - make`,
+		},
+		{
+			title: "valid backup",
+			input: `uri: https://example.com/repo.git
+backup:
+  format: tar.gz
+  dir: ${HOME}/backups
+install:
+  - make`,
+		},
+		{
+			title: "unknown backup format",
+			input: `uri: https://example.com/repo.git
+backup:
+  format: zip
+install:
+  - make`,
+			want: []string{
+				"backup: format",
+			},
+		},
+		{
+			title: "all problems",
+			input: `uri: https://example.com/repo.git
+instal:
+  - make
+shell: [no-such-shell-ivg]
+locald: ../outside
+lock: /tmp/lock
+install:
+  - make
+  - " "`,
+			want: []string{
+				"unknown key $.instal",
+				"shell:",
+				"locald: ../outside",
+				"lock: /tmp/lock",
+				"install[1]: empty step",
+			},
+		},
+	} {
+		t.Run(tc.title, func(t *testing.T) {
+			got := config.Validate(strings.NewReader(tc.input))
+			if !assert.Equal(t, len(tc.want), len(got), "%v", got) {
+				return
+			}
+			for i, w := range tc.want {
+				assert.Contains(t, got[i].Error(), w)
+			}
+		})
+	}
+}
+
+func TestSchema(t *testing.T) {
+	s := config.NewSchema()
+	assert.Equal(t, "object", s.Type)
+	assert.Equal(t, false, s.AdditionalProperties)
+	for _, k := range []string{
+		"extends", "uri", "branch", "locald", "lock", "env", "shell", "auth", "backup", "secrets",
+		"setup", "install", "rollback", "skip", "check", "uninstall",
+	} {
+		assert.Contains(t, s.Properties, k)
+	}
+	assert.NotContains(t, s.Properties, "steps")
+	assert.Len(t, s.Properties["uri"].OneOf, 2)
+	assert.Equal(t, "string", s.Properties["env"].AdditionalProperties.(*config.Schema).Type)
+	assert.Contains(t, s.Properties["auth"].Properties, "tokenEnv")
+	assert.Contains(t, s.Properties["backup"].Properties, "format")
+}
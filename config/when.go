@@ -0,0 +1,140 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path"
+	"runtime"
+	"slices"
+)
+
+// When is the condition of a step or the whole config.
+//
+// Each field matches if any of the values matches, When matches if all the fields match.
+// Empty fields always match.
+type When struct {
+	// OS is the values of GOOS, e.g. linux, darwin.
+	OS Strings `yaml:"os,omitempty" json:"os,omitempty"`
+	// Arch is the values of GOARCH, e.g. amd64, arm64.
+	Arch Strings `yaml:"arch,omitempty" json:"arch,omitempty"`
+	// Host is the glob patterns of the hostname.
+	Host Strings `yaml:"host,omitempty" json:"host,omitempty"`
+	// Env is the names of the environment variables to be set.
+	Env Strings `yaml:"env,omitempty" json:"env,omitempty"`
+}
+
+// Platform is the facts When matches against.
+type Platform struct {
+	OS   string
+	Arch string
+	Host string
+	Env  map[string]string
+}
+
+// NewPlatform returns the current platform.
+// Env is looked up before the process environment.
+func NewPlatform(env map[string]string) Platform {
+	host, _ := os.Hostname()
+	return Platform{
+		OS:   runtime.GOOS,
+		Arch: runtime.GOARCH,
+		Host: host,
+		Env:  env,
+	}
+}
+
+func (p Platform) hasEnv(name string) bool {
+	if _, ok := p.Env[name]; ok {
+		return true
+	}
+	_, ok := os.LookupEnv(name)
+	return ok
+}
+
+// Match returns true if the platform satisfies the condition.
+// Nil When always matches.
+func (w *When) Match(p Platform) bool {
+	if w == nil {
+		return true
+	}
+	if len(w.OS) > 0 && !slices.Contains(w.OS, p.OS) {
+		return false
+	}
+	if len(w.Arch) > 0 && !slices.Contains(w.Arch, p.Arch) {
+		return false
+	}
+	if len(w.Host) > 0 && !slices.ContainsFunc(w.Host, func(pattern string) bool {
+		ok, _ := path.Match(pattern, p.Host)
+		return ok
+	}) {
+		return false
+	}
+	if len(w.Env) > 0 && !slices.ContainsFunc(w.Env, p.hasEnv) {
+		return false
+	}
+	return true
+}
+
+// Step is a script of the steps.
+// It is written as a string, or a mapping of run and when.
+type Step struct {
+	Run  string `yaml:"run" json:"run"`
+	When *When  `yaml:"when,omitempty" json:"when,omitempty"`
+}
+
+// stepObject is Step without the custom marshalers.
+type stepObject Step
+
+func (s *Step) UnmarshalYAML(unmarshal func(any) error) error {
+	var run string
+	if err := unmarshal(&run); err == nil {
+		*s = Step{Run: run}
+		return nil
+	}
+	var x stepObject
+	if err := unmarshal(&x); err != nil {
+		return errors.Join(ErrParse, err)
+	}
+	*s = Step(x)
+	return nil
+}
+
+func (s Step) MarshalYAML() (any, error) {
+	if s.When == nil {
+		return s.Run, nil
+	}
+	return stepObject(s), nil
+}
+
+func (s *Step) UnmarshalJSON(b []byte) error {
+	var run string
+	if err := json.Unmarshal(b, &run); err == nil {
+		*s = Step{Run: run}
+		return nil
+	}
+	var x stepObject
+	if err := json.Unmarshal(b, &x); err != nil {
+		return errors.Join(ErrParse, err)
+	}
+	*s = Step(x)
+	return nil
+}
+
+func (s Step) MarshalJSON() ([]byte, error) {
+	if s.When == nil {
+		return json.Marshal(s.Run)
+	}
+	return json.Marshal(stepObject(s))
+}
+
+// Scripts returns the scripts of the steps matching the platform.
+func Scripts(steps []Step, p Platform) []string {
+	var r []string
+	for _, s := range steps {
+		if s.When.Match(p) {
+			r = append(r, s.Run)
+		}
+	}
+	return r
+}
@@ -0,0 +1,114 @@
+package config_test
+
+import (
+	"berquerant/install-via-git-go/config"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/goccy/go-yaml"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWhen(t *testing.T) {
+	platform := config.Platform{
+		OS:   "linux",
+		Arch: "arm64",
+		Host: "build-01.example.com",
+		Env: map[string]string{
+			"CI": "true",
+		},
+	}
+
+	for _, tc := range []struct {
+		title string
+		when  *config.When
+		want  bool
+	}{
+		{title: "nil", want: true},
+		{title: "empty", when: &config.When{}, want: true},
+		{title: "os", when: &config.When{OS: config.Strings{"darwin", "linux"}}, want: true},
+		{title: "os mismatch", when: &config.When{OS: config.Strings{"darwin"}}},
+		{title: "arch", when: &config.When{Arch: config.Strings{"arm64"}}, want: true},
+		{title: "arch mismatch", when: &config.When{Arch: config.Strings{"amd64"}}},
+		{title: "host", when: &config.When{Host: config.Strings{"build-*"}}, want: true},
+		{title: "host mismatch", when: &config.When{Host: config.Strings{"dev-*"}}},
+		{title: "env", when: &config.When{Env: config.Strings{"CI"}}, want: true},
+		{title: "env mismatch", when: &config.When{Env: config.Strings{"IVG_TEST_NO_SUCH_ENV"}}},
+		{
+			title: "all",
+			when: &config.When{
+				OS:   config.Strings{"linux"},
+				Arch: config.Strings{"arm64"},
+				Host: config.Strings{"*.example.com"},
+				Env:  config.Strings{"CI"},
+			},
+			want: true,
+		},
+		{
+			title: "any mismatch",
+			when: &config.When{
+				OS:   config.Strings{"linux"},
+				Arch: config.Strings{"amd64"},
+			},
+		},
+	} {
+		t.Run(tc.title, func(t *testing.T) {
+			assert.Equal(t, tc.want, tc.when.Match(platform))
+		})
+	}
+}
+
+func TestStep(t *testing.T) {
+	const input = `uri: https://example.com/repo.git
+when:
+  os: linux
+install:
+  - make
+  - run: make arm
+    when:
+      arch: [arm64]
+  - run: make amd
+    when:
+      arch: amd64`
+	cfg, err := config.Parse(strings.NewReader(input))
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, &config.When{OS: config.Strings{"linux"}}, cfg.When)
+	assert.Equal(t, []config.Step{
+		{Run: "make"},
+		{Run: "make arm", When: &config.When{Arch: config.Strings{"arm64"}}},
+		{Run: "make amd", When: &config.When{Arch: config.Strings{"amd64"}}},
+	}, cfg.Steps.Install)
+	assert.Equal(t, []string{"make", "make arm"}, config.Scripts(cfg.Steps.Install, config.Platform{Arch: "arm64"}))
+
+	t.Run("marshal", func(t *testing.T) {
+		y, err := yaml.Marshal(cfg)
+		if !assert.Nil(t, err) {
+			return
+		}
+		got, err := config.Parse(strings.NewReader(string(y)))
+		if assert.Nil(t, err, string(y)) {
+			assert.Equal(t, cfg.Steps, got.Steps)
+		}
+		j, err := json.Marshal(cfg)
+		if !assert.Nil(t, err) {
+			return
+		}
+		got, err = config.Parse(strings.NewReader(string(j)))
+		if assert.Nil(t, err, string(j)) {
+			assert.Equal(t, cfg.Steps, got.Steps)
+		}
+	})
+
+	t.Run("unknown key", func(t *testing.T) {
+		_, err := config.Parse(strings.NewReader(`uri: https://example.com/repo.git
+install:
+  - run: make
+    when:
+      platform: linux`))
+		assert.ErrorIs(t, err, config.ErrParse)
+		assert.ErrorContains(t, err, "-:5:7: unknown key $.install[0].when.platform")
+	})
+}
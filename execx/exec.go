@@ -71,8 +71,11 @@ func run(ctx context.Context, cmd *ex.Cmd) (result Result, retErr error) {
 		logx.S("dir", cmd.Dir),
 		logx.SS("args", cmd.Args),
 	)
+	env := cmd.Env.IntoSlice()
+	// mask secrets in env and also in the outputs
+	logx.AddSecretEnv(env)
 	logx.Debug("exec start",
-		logx.SS("env", cmd.Env.IntoSlice()),
+		logx.SS("env", env),
 	)
 	defer func() {
 		logx.Info("exec end", logx.Err(retErr))
@@ -65,6 +65,76 @@ func (f FilePath) Write(str string) error {
 	return err
 }
 
+// WriteAtomic overwrites the file via a temporary file, fsync and rename,
+// not to leave the truncated file when the process crashes or the disk is full.
+func (f FilePath) WriteAtomic(str string) error {
+	err := f.writeAtomic(0600, func(w io.Writer) error {
+		_, err := io.WriteString(w, str)
+		return err
+	})
+	logx.Debug("write file atomic", logx.S("path", f.String()), logx.S("content", str), logx.Err(err))
+	return err
+}
+
+// CopyAtomic copies the file into dst like WriteAtomic, keeping the mode.
+func (f FilePath) CopyAtomic(dst FilePath) error {
+	err := func() error {
+		in, err := os.Open(f.String())
+		if err != nil {
+			return err
+		}
+		defer in.Close()
+		stat, err := in.Stat()
+		if err != nil {
+			return err
+		}
+		return dst.writeAtomic(stat.Mode().Perm(), func(w io.Writer) error {
+			_, err := io.Copy(w, in)
+			return err
+		})
+	}()
+	logx.Debug("copy file atomic",
+		logx.S("src", f.String()),
+		logx.S("dst", dst.String()),
+		logx.Err(err),
+	)
+	return err
+}
+
+func (f FilePath) writeAtomic(perm os.FileMode, write func(io.Writer) error) (retErr error) {
+	tmp, err := os.CreateTemp(f.DirPath().String(), "."+f.Tail()+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if retErr != nil {
+			_ = tmp.Close()
+			_ = os.Remove(tmp.Name())
+		}
+	}()
+	if err := write(tmp); err != nil {
+		return err
+	}
+	if err := tmp.Chmod(perm); err != nil {
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), f.String()); err != nil {
+		return err
+	}
+	// persist the rename, not supported on some platforms
+	if dir, err := os.Open(f.DirPath().String()); err == nil {
+		_ = dir.Sync()
+		_ = dir.Close()
+	}
+	return nil
+}
+
 func (f FilePath) Remove() error {
 	err := os.Remove(f.String())
 	logx.Debug("remove file", logx.S("path", f.String()), logx.Err(err))
@@ -6,6 +6,7 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 )
 
 type Path string
@@ -84,3 +85,11 @@ func (p Path) Rename(dst Path) error {
 	)
 	return err
 }
+
+// Touch updates the access and modification times to now.
+func (p Path) Touch() error {
+	now := time.Now()
+	err := os.Chtimes(p.String(), now, now)
+	logx.Debug("touch", logx.S("path", p.String()), logx.Err(err))
+	return err
+}
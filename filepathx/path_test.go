@@ -46,6 +46,28 @@ func TestPath(t *testing.T) {
 			assert.Nil(t, err)
 			assert.Equal(t, "str", got)
 		})
+
+		t.Run("WriteAtomic", func(t *testing.T) {
+			assert.Nil(t, p.WriteAtomic("atomic"))
+			got, err := p.Read()
+			assert.Nil(t, err)
+			assert.Equal(t, "atomic", got)
+
+			assert.Nil(t, os.Chmod(p.String(), 0750))
+			dst := path.Join("copied").FilePath()
+			assert.Nil(t, p.CopyAtomic(dst))
+			got, err = dst.Read()
+			assert.Nil(t, err)
+			assert.Equal(t, "atomic", got)
+			stat, err := os.Stat(dst.String())
+			assert.Nil(t, err)
+			assert.Equal(t, os.FileMode(0750), stat.Mode().Perm())
+
+			// no temporary files left
+			entries, err := os.ReadDir(path.String())
+			assert.Nil(t, err)
+			assert.Len(t, entries, 2)
+		})
 	})
 
 	t.Run("Join", func(t *testing.T) {
@@ -0,0 +1,160 @@
+package gc
+
+import (
+	"berquerant/install-via-git-go/backup"
+	"berquerant/install-via-git-go/errorx"
+	"berquerant/install-via-git-go/filepathx"
+	"berquerant/install-via-git-go/history"
+	"berquerant/install-via-git-go/worktree"
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+var (
+	ErrGC = errors.New("GC")
+)
+
+type Kind string
+
+const (
+	// KindBackup is a temporary directory of backup left by a killed process.
+	KindBackup Kind = "backup"
+	// KindWorktree is a worktree not linked.
+	KindWorktree Kind = "worktree"
+)
+
+// Target is a directory to be removed.
+type Target struct {
+	Kind   Kind
+	Path   filepathx.DirPath
+	Reason string
+	remove func(ctx context.Context) error
+}
+
+func (t *Target) Remove(ctx context.Context) error {
+	if err := t.remove(ctx); err != nil {
+		return errorx.Errorf(errors.Join(ErrGC, err), "remove %s", t.Path)
+	}
+	return nil
+}
+
+// Size returns the total size of the files under the path.
+func (t *Target) Size() (int64, error) {
+	var size int64
+	err := filepath.WalkDir(t.Path.String(), func(_ string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.Type().IsRegular() {
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			size += info.Size()
+		}
+		return nil
+	})
+	return size, err
+}
+
+// Backups returns the temporary directories of backup of owner in dir not modified for age, except inUse.
+// The backups of the other owners, e.g. the other workDirs sharing the system temp dir, are not returned.
+// The younger ones may be used by the running processes,
+// inUse are the ones to be restored, e.g. the backups in the journal of the interrupted run.
+func Backups(dir filepathx.DirPath, owner string, now time.Time, age time.Duration, inUse []string) ([]*Target, error) {
+	entries, err := os.ReadDir(dir.String())
+	if err != nil {
+		return nil, errorx.Errorf(errors.Join(ErrGC, err), "read %s", dir)
+	}
+	var (
+		targets []*Target
+		prefix  = backup.OwnerPattern(owner)
+	)
+	for _, entry := range entries {
+		if !entry.IsDir() || !strings.HasPrefix(entry.Name(), prefix) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil || now.Sub(info.ModTime()) < age {
+			continue
+		}
+		path := dir.Join(entry.Name()).DirPath()
+		if slices.ContainsFunc(inUse, func(x string) bool {
+			return filepath.Clean(x) == path.String()
+		}) {
+			continue
+		}
+		targets = append(targets, &Target{
+			Kind:   KindBackup,
+			Path:   path,
+			Reason: "orphaned since " + info.ModTime().Format(time.RFC3339),
+			remove: func(context.Context) error {
+				return path.Remove()
+			},
+		})
+	}
+	return targets, nil
+}
+
+// Worktrees returns the worktrees of stage to be removed:
+// the ones never installed successfully according to entries,
+// and the ones beyond the keep latest installed, the linked one is always kept.
+func Worktrees(stage *worktree.Stage, entries []*history.Entry, keep int) ([]*Target, error) {
+	hashes, err := stage.List()
+	if err != nil {
+		return nil, errors.Join(ErrGC, err)
+	}
+	current, _ := stage.Current()
+
+	// the last successful installation of the commits
+	installed := map[string]time.Time{}
+	for _, entry := range entries {
+		if entry.Installed() {
+			installed[entry.To] = entry.Time
+		}
+	}
+	var (
+		targets []*Target
+		kept    []string
+	)
+	newTarget := func(hash, reason string) *Target {
+		return &Target{
+			Kind:   KindWorktree,
+			Path:   stage.Path(hash),
+			Reason: reason,
+			remove: func(ctx context.Context) error {
+				return stage.Remove(ctx, hash)
+			},
+		}
+	}
+	for _, hash := range hashes {
+		if hash == current {
+			continue
+		}
+		if _, ok := installed[hash]; !ok {
+			targets = append(targets, newTarget(hash, "not installed"))
+			continue
+		}
+		kept = append(kept, hash)
+	}
+	// latest first
+	slices.SortStableFunc(kept, func(a, b string) int {
+		return installed[b].Compare(installed[a])
+	})
+	if current != "" {
+		keep--
+	}
+	for i, hash := range kept {
+		if i < max(keep, 0) {
+			continue
+		}
+		targets = append(targets, newTarget(hash, "installed at "+installed[hash].Format(time.RFC3339)))
+	}
+	return targets, nil
+}
@@ -0,0 +1,128 @@
+package gc_test
+
+import (
+	"berquerant/install-via-git-go/backup"
+	"berquerant/install-via-git-go/execx"
+	"berquerant/install-via-git-go/filepathx"
+	"berquerant/install-via-git-go/gc"
+	"berquerant/install-via-git-go/git"
+	"berquerant/install-via-git-go/history"
+	"berquerant/install-via-git-go/worktree"
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBackups(t *testing.T) {
+	dir, err := filepathx.NewPath(t.TempDir())
+	if !assert.Nil(t, err) {
+		return
+	}
+	now := time.Now()
+	const owner = "/work"
+	prefix := backup.OwnerPattern(owner)
+	for name, mtime := range map[string]time.Time{
+		prefix + "1":                        now.Add(-2 * time.Hour),
+		prefix + "2":                        now,
+		prefix + "3":                        now.Add(-2 * time.Hour),
+		backup.OwnerPattern("/other") + "4": now.Add(-2 * time.Hour),
+		backup.TempDirPattern + "5":         now.Add(-2 * time.Hour),
+		"other":                             now.Add(-2 * time.Hour),
+	} {
+		p := dir.Join(name)
+		assert.Nil(t, p.DirPath().Ensure())
+		assert.Nil(t, os.WriteFile(p.Join("file").String(), []byte("12345"), 0600))
+		assert.Nil(t, os.Chtimes(p.String(), mtime, mtime))
+	}
+
+	// 3 is in the journal, 4 and 5 are not of owner
+	got, err := gc.Backups(dir.DirPath(), owner, now, time.Hour, []string{dir.Join(prefix+"3").String() + "/"})
+	if !assert.Nil(t, err) {
+		return
+	}
+	if !assert.Len(t, got, 1) {
+		return
+	}
+	assert.Equal(t, gc.KindBackup, got[0].Kind)
+	assert.Equal(t, dir.Join(prefix+"1").DirPath(), got[0].Path)
+	size, err := got[0].Size()
+	assert.Nil(t, err)
+	assert.Equal(t, int64(5), size)
+
+	assert.Nil(t, got[0].Remove(context.TODO()))
+	assert.False(t, got[0].Path.Exist())
+}
+
+func TestWorktrees(t *testing.T) {
+	dir, err := filepathx.NewPath(t.TempDir())
+	if !assert.Nil(t, err) {
+		return
+	}
+	link := dir.Join("repo")
+	for _, hash := range []string{"a", "b", "c", "d", "failed"} {
+		assert.Nil(t, worktree.Dir(link).Join(hash).DirPath().Ensure())
+	}
+	assert.Nil(t, worktree.RepoDir(link).Ensure())
+	stage := worktree.NewStage(link, git.NewCLI(worktree.RepoDir(link), execx.NewEnv(), "git"))
+	assert.Nil(t, stage.Swap("b"))
+
+	now := time.Now()
+	entries := []*history.Entry{
+		{Time: now, To: "a", Outcome: history.OutcomeSuccess},
+		{Time: now.Add(time.Minute), To: "b", Outcome: history.OutcomeSuccess},
+		{Time: now.Add(2 * time.Minute), To: "c", Outcome: history.OutcomeSuccess},
+		{Time: now.Add(3 * time.Minute), To: "failed", Outcome: history.OutcomeRollback},
+		{Time: now.Add(4 * time.Minute), To: "d", Outcome: history.OutcomeSuccess},
+		// rollback to b
+		{Time: now.Add(5 * time.Minute), To: "b", Outcome: history.OutcomeSuccess},
+		// not installs
+		{Time: now.Add(6 * time.Minute), To: "a", Outcome: history.OutcomeNoop},
+		{Time: now.Add(8 * time.Minute), Outcome: history.OutcomeFailure},
+	}
+
+	for _, tc := range []struct {
+		title string
+		keep  int
+		want  []string
+	}{
+		{
+			title: "keep 1",
+			keep:  1,
+			want:  []string{"failed", "d", "c", "a"},
+		},
+		{
+			title: "keep 2",
+			keep:  2,
+			want:  []string{"failed", "c", "a"},
+		},
+		{
+			title: "keep all",
+			keep:  10,
+			want:  []string{"failed"},
+		},
+		{
+			title: "keep 0",
+			want:  []string{"failed", "d", "c", "a"},
+		},
+	} {
+		t.Run(tc.title, func(t *testing.T) {
+			got, err := gc.Worktrees(stage, entries, tc.keep)
+			if !assert.Nil(t, err) {
+				return
+			}
+			paths := make([]filepathx.DirPath, len(got))
+			for i, x := range got {
+				assert.Equal(t, gc.KindWorktree, x.Kind)
+				paths[i] = x.Path
+			}
+			want := make([]filepathx.DirPath, len(tc.want))
+			for i, x := range tc.want {
+				want[i] = stage.Path(x)
+			}
+			assert.Equal(t, want, paths)
+		})
+	}
+}
@@ -0,0 +1,99 @@
+package git
+
+import (
+	"berquerant/install-via-git-go/execx"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+var ErrNoToken = errors.New("NoToken")
+
+// Auth provides credentials to git invocations.
+type Auth struct {
+	// URL is the remote the authorization header is sent to, also the remotes under it.
+	// The header is not sent if empty, not to leak the token to the other hosts like the fallbacks.
+	URL string
+	// Username and Token are sent as the basic authorization header for URL.
+	Username string
+	Token    string
+	// SSHKey is the private key for ssh remotes.
+	SSHKey string
+	// KnownHosts is the known_hosts file for ssh remotes.
+	KnownHosts string
+}
+
+// Header returns the value of the authorization header.
+func (a *Auth) Header() string {
+	if a.Token == "" {
+		return ""
+	}
+	cred := base64.StdEncoding.EncodeToString([]byte(a.Username + ":" + a.Token))
+	return "Authorization: Basic " + cred
+}
+
+// Secrets returns the values to be masked in the logs.
+func (a *Auth) Secrets() []string {
+	if a.Token == "" {
+		return nil
+	}
+	return []string{
+		a.Token,
+		base64.StdEncoding.EncodeToString([]byte(a.Username + ":" + a.Token)),
+	}
+}
+
+// Env returns the environment variables to apply the credentials to a git invocation with base,
+// appended to GIT_CONFIG_COUNT of base or the process environment.
+func (a *Auth) Env(base execx.Env) execx.Env {
+	env := execx.NewEnv()
+	env.Set("GIT_TERMINAL_PROMPT", "0")
+	if header := a.Header(); header != "" && a.URL != "" {
+		// same as git -c http.URL.extraHeader=header, but does not appear in args
+		count := configCount(base)
+		env.Set("GIT_CONFIG_COUNT", strconv.Itoa(count+1))
+		env.Set(fmt.Sprintf("GIT_CONFIG_KEY_%d", count), "http."+a.URL+".extraHeader")
+		env.Set(fmt.Sprintf("GIT_CONFIG_VALUE_%d", count), header)
+	}
+	if ssh := a.sshCommand(); ssh != "" {
+		env.Set("GIT_SSH_COMMAND", ssh)
+	}
+	return env
+}
+
+// configCount returns GIT_CONFIG_COUNT of base or the process environment, 0 if not set or invalid.
+func configCount(base execx.Env) int {
+	v, ok := base.Get("GIT_CONFIG_COUNT")
+	if !ok {
+		v = os.Getenv("GIT_CONFIG_COUNT")
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 0 {
+		return 0
+	}
+	return n
+}
+
+func (a *Auth) sshCommand() string {
+	if a.SSHKey == "" && a.KnownHosts == "" {
+		return ""
+	}
+	args := []string{"ssh"}
+	if a.SSHKey != "" {
+		args = append(args, "-i", quote(a.SSHKey), "-o", "IdentitiesOnly=yes")
+	}
+	if a.KnownHosts != "" {
+		args = append(args,
+			"-o", "UserKnownHostsFile="+quote(a.KnownHosts),
+			"-o", "StrictHostKeyChecking=yes",
+		)
+	}
+	return strings.Join(args, " ")
+}
+
+func quote(s string) string {
+	return fmt.Sprintf("'%s'", strings.ReplaceAll(s, "'", `'\''`))
+}
@@ -0,0 +1,93 @@
+package git_test
+
+import (
+	"berquerant/install-via-git-go/execx"
+	"berquerant/install-via-git-go/git"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAuth(t *testing.T) {
+	for _, tc := range []struct {
+		title string
+		auth  *git.Auth
+		base  map[string]string
+		want  map[string]string
+	}{
+		{
+			title: "empty",
+			auth:  &git.Auth{},
+			want: map[string]string{
+				"GIT_TERMINAL_PROMPT": "0",
+			},
+		},
+		{
+			title: "token",
+			auth: &git.Auth{
+				URL:      "https://example.com/repo.git",
+				Username: "user",
+				Token:    "token",
+			},
+			want: map[string]string{
+				"GIT_TERMINAL_PROMPT": "0",
+				"GIT_CONFIG_COUNT":    "1",
+				"GIT_CONFIG_KEY_0":    "http.https://example.com/repo.git.extraHeader",
+				"GIT_CONFIG_VALUE_0":  "Authorization: Basic dXNlcjp0b2tlbg==",
+			},
+		},
+		{
+			title: "token without url",
+			auth: &git.Auth{
+				Username: "user",
+				Token:    "token",
+			},
+			want: map[string]string{
+				"GIT_TERMINAL_PROMPT": "0",
+			},
+		},
+		{
+			title: "append to config",
+			auth: &git.Auth{
+				URL:      "https://example.com/repo.git",
+				Username: "user",
+				Token:    "token",
+			},
+			base: map[string]string{
+				"GIT_CONFIG_COUNT": "2",
+			},
+			want: map[string]string{
+				"GIT_TERMINAL_PROMPT": "0",
+				"GIT_CONFIG_COUNT":    "3",
+				"GIT_CONFIG_KEY_2":    "http.https://example.com/repo.git.extraHeader",
+				"GIT_CONFIG_VALUE_2":  "Authorization: Basic dXNlcjp0b2tlbg==",
+			},
+		},
+		{
+			title: "ssh",
+			auth: &git.Auth{
+				SSHKey:     "/path/to/id_ed25519",
+				KnownHosts: "/path/to/known_hosts",
+			},
+			want: map[string]string{
+				"GIT_TERMINAL_PROMPT": "0",
+				"GIT_SSH_COMMAND":     "ssh -i '/path/to/id_ed25519' -o IdentitiesOnly=yes -o UserKnownHostsFile='/path/to/known_hosts' -o StrictHostKeyChecking=yes",
+			},
+		},
+	} {
+		t.Run(tc.title, func(t *testing.T) {
+			t.Setenv("GIT_CONFIG_COUNT", "")
+			base := execx.NewEnv()
+			for k, v := range tc.base {
+				base.Set(k, v)
+			}
+			got := tc.auth.Env(base)
+			assert.Equal(t, len(tc.want), len(got))
+			for k, v := range tc.want {
+				x, ok := got.Get(k)
+				assert.True(t, ok, k)
+				assert.Equal(t, v, x, k)
+			}
+		})
+	}
+}
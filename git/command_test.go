@@ -0,0 +1,135 @@
+package git_test
+
+import (
+	"berquerant/install-via-git-go/execx"
+	"berquerant/install-via-git-go/filepathx"
+	"berquerant/install-via-git-go/git"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newCommand(t *testing.T, dir string, opt ...git.ConfigOption) git.Command {
+	t.Helper()
+	p, err := filepathx.NewPath(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return git.NewCommand(git.NewCLI(p.DirPath(), execx.NewEnv(), "git", opt...), opt...)
+}
+
+func TestCommandFetchFallback(t *testing.T) {
+	base, err := filepathx.NewPath(t.TempDir())
+	if !assert.Nil(t, err) {
+		return
+	}
+	src := base.Join("src").String()
+	local := base.Join("local").String()
+	env := execx.EnvFromSlice([]string{
+		"GIT_AUTHOR_NAME=ivg",
+		"GIT_AUTHOR_EMAIL=ivg@example.com",
+		"GIT_COMMITTER_NAME=ivg",
+		"GIT_COMMITTER_EMAIL=ivg@example.com",
+	})
+	r, err := execx.NewExecutorFromStrings([]string{
+		"git init -q -b main src",
+		"cd src",
+		"echo 1 > f && git add f && git commit -q -m 1",
+		"git clone -q . ../local",
+		"git checkout -q -b feature",
+		"echo feature > f && git commit -q -am feature",
+		"git tag v1",
+		"git rev-parse HEAD",
+		"git checkout -q main",
+		"git -C ../local remote set-url origin ../notfound",
+	}, "bash").Execute(context.TODO(), execx.WithDir(base.DirPath()), execx.WithEnv(env))
+	if !assert.Nil(t, err) {
+		return
+	}
+	feature := strings.TrimSpace(r.Stdout)
+
+	ctx := context.TODO()
+	cmd := newCommand(t, local, git.WithFallbacks([]string{src}))
+	if !assert.Nil(t, cmd.Fetch(ctx)) {
+		return
+	}
+	assert.Equal(t, src, cmd.Provider())
+	for _, rev := range []string{"origin/feature", "v1"} {
+		got, err := cmd.ResolveCommit(ctx, rev)
+		assert.Nil(t, err, rev)
+		assert.Equal(t, feature, got, rev)
+	}
+	assert.Nil(t, cmd.Checkout(ctx, "feature"))
+	got, err := cmd.GetCommitHash(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, feature, got)
+}
+
+func TestCommandLog(t *testing.T) {
+	dir, err := filepathx.NewPath(t.TempDir())
+	if !assert.Nil(t, err) {
+		return
+	}
+	env := execx.EnvFromSlice([]string{
+		"GIT_AUTHOR_NAME=ivg",
+		"GIT_AUTHOR_EMAIL=ivg@example.com",
+		"GIT_COMMITTER_NAME=ivg",
+		"GIT_COMMITTER_EMAIL=ivg@example.com",
+	})
+	r, err := execx.NewExecutorFromStrings([]string{
+		"git init -q -b main",
+		"echo 1 > f && git add f && git commit -q -m 'f 1'",
+		"git rev-parse HEAD",
+		"echo 2 > f && git commit -q -am 'f 2'",
+		"echo 3 > g && git add g && git commit -q -m 'g 3'",
+		"git rev-parse HEAD",
+	}, "bash").Execute(context.TODO(), execx.WithDir(dir.DirPath()), execx.WithEnv(env))
+	if !assert.Nil(t, err) {
+		return
+	}
+	hashes := strings.Fields(r.Stdout)
+	if !assert.Len(t, hashes, 2) {
+		return
+	}
+	first, third := hashes[0], hashes[1]
+
+	ctx := context.TODO()
+	cmd := newCommand(t, dir.String())
+
+	t.Run("log", func(t *testing.T) {
+		got, err := cmd.Log(ctx, first, third)
+		if !assert.Nil(t, err) {
+			return
+		}
+		lines := strings.Split(got, "\n")
+		if !assert.Len(t, lines, 2) {
+			return
+		}
+		assert.True(t, strings.HasPrefix(third, strings.Fields(lines[0])[0]), lines[0])
+		assert.True(t, strings.HasSuffix(lines[0], " g 3"), lines[0])
+		assert.True(t, strings.HasSuffix(lines[1], " f 2"), lines[1])
+	})
+
+	t.Run("log ancestor", func(t *testing.T) {
+		got, err := cmd.Log(ctx, third, first)
+		assert.Nil(t, err)
+		assert.Equal(t, "", got)
+	})
+
+	t.Run("diffstat", func(t *testing.T) {
+		got, err := cmd.DiffStat(ctx, first, third)
+		if !assert.Nil(t, err) {
+			return
+		}
+		assert.Contains(t, got, "f | 2 +-")
+		assert.Contains(t, got, "g | 1 +")
+		assert.Contains(t, got, "2 files changed")
+	})
+
+	t.Run("unknown", func(t *testing.T) {
+		_, err := cmd.Log(ctx, first, "unknown")
+		assert.NotNil(t, err)
+	})
+}
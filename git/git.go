@@ -1,25 +1,33 @@
 package git
 
 import (
+	"berquerant/install-via-git-go/errorx"
 	"berquerant/install-via-git-go/execx"
 	"berquerant/install-via-git-go/filepathx"
+	"berquerant/install-via-git-go/logx"
 	"context"
 	"errors"
+	"path/filepath"
 	"strings"
 )
 
 type CLI interface {
 	Execute(ctx context.Context, args ...string) (string, error)
+	// ExecuteIn executes git in dir instead of Dir.
+	ExecuteIn(ctx context.Context, dir filepathx.DirPath, args ...string) (string, error)
 	Command() string
 	Dir() filepathx.DirPath
 	Env() execx.Env
 }
 
-func NewCLI(dir filepathx.DirPath, env execx.Env, command string) *CLIImpl {
+func NewCLI(dir filepathx.DirPath, env execx.Env, command string, opt ...ConfigOption) *CLIImpl {
+	config := NewConfigBuilder().Auth(nil).Build()
+	config.Apply(opt...)
 	return &CLIImpl{
 		dir:     dir,
 		command: command,
 		env:     env,
+		auth:    config.Auth.Get(),
 	}
 }
 
@@ -27,10 +35,12 @@ type CLIImpl struct {
 	command string
 	dir     filepathx.DirPath
 	env     execx.Env
+	auth    *Auth
 }
 
 var (
-	ErrCLI = errors.New("GitCLI")
+	ErrCLI     = errors.New("GitCLI")
+	ErrOffline = errors.New("Offline")
 )
 
 func (c CLIImpl) Env() execx.Env {
@@ -46,57 +56,214 @@ func (c CLIImpl) Dir() filepathx.DirPath {
 }
 
 func (c CLIImpl) Execute(ctx context.Context, args ...string) (string, error) {
+	return c.ExecuteIn(ctx, c.dir, args...)
+}
+
+func (c CLIImpl) ExecuteIn(ctx context.Context, dir filepathx.DirPath, args ...string) (string, error) {
 	r, err := execx.NewCommand(
 		append([]string{c.command}, args...)...,
 	).
-		Execute(ctx, execx.WithDir(c.dir), execx.WithEnv(c.env))
+		Execute(ctx, execx.WithDir(dir), execx.WithEnv(c.gitEnv()))
 	if err != nil {
 		return "", err
 	}
 	return strings.TrimSpace(r.Stdout), nil
 }
 
+// gitEnv returns Env with the credentials, only for git invocations.
+func (c CLIImpl) gitEnv() execx.Env {
+	if c.auth == nil {
+		return c.env
+	}
+	env := execx.NewEnv()
+	env.Merge(c.env)
+	env.Merge(c.auth.Env(c.env))
+	return env
+}
+
+// Mirror provides a local repository to borrow objects from when cloning.
+type Mirror interface {
+	// Sync updates the mirror of uri and returns it.
+	Sync(ctx context.Context, uri string) (filepathx.DirPath, error)
+	// Lookup returns the mirror of uri without updating.
+	Lookup(uri string) (filepathx.DirPath, bool)
+}
+
+//go:generate go tool goconfig -field "Mirror Mirror|Offline bool|Fallbacks []string|Auth *Auth" -option -output git_config_generated.go
+
 type Command interface {
 	Clone(ctx context.Context, repo string) error
 	GetCommitHash(ctx context.Context) (string, error)
+	// ResolveCommit returns the full hash of the commit rev points to,
+	// rev is a hash, an abbreviated hash, a tag or a branch.
+	ResolveCommit(ctx context.Context, rev string) (string, error)
 	Fetch(ctx context.Context) error
 	Checkout(ctx context.Context, commit string) error
 	ResetHard(ctx context.Context, commit string) error
 	PullForce(ctx context.Context, repo string) error
+	// Provider returns the remote which provided the commits by the last Clone, Fetch or PullForce.
+	Provider() string
+	// DefaultBranch returns the branch HEAD of repo points to, without cloning.
+	DefaultBranch(ctx context.Context, repo string) (string, error)
+	// HasRef returns true if repo has the branch or the tag, without cloning.
+	HasRef(ctx context.Context, repo, ref string) (bool, error)
+	// Log returns the commits reachable from to but not from from, newest first, one line per commit.
+	Log(ctx context.Context, from, to string) (string, error)
+	// DiffStat returns the diffstat between from and to.
+	DiffStat(ctx context.Context, from, to string) (string, error)
 	CLI() CLI
 }
 
-func NewCommand(cli CLI) *CommandImpl {
+func NewCommand(cli CLI, opt ...ConfigOption) *CommandImpl {
+	config := NewConfigBuilder().Mirror(nil).Offline(false).Fallbacks(nil).Build()
+	config.Apply(opt...)
 	return &CommandImpl{
-		cli: cli,
+		cli:       cli,
+		mirror:    config.Mirror.Get(),
+		offline:   config.Offline.Get(),
+		fallbacks: config.Fallbacks.Get(),
+		state:     &commandState{},
 	}
 }
 
+// CommandImpl is a Command by git CLI.
+//
+// Clone, Fetch and PullForce try the repo (origin) first, then the fallbacks in order.
+// The origin of the local repo is always the repo even if cloned from a fallback.
+//
+// If offline, CommandImpl never touches the network:
+// Clone clones from the mirror only, Fetch and PullForce do nothing.
 type CommandImpl struct {
-	cli CLI
+	cli       CLI
+	mirror    Mirror
+	offline   bool
+	fallbacks []string
+	state     *commandState
+}
+
+type commandState struct {
+	provider string
 }
 
 func (c CommandImpl) CLI() CLI {
 	return c.cli
 }
 
+func (c CommandImpl) Provider() string {
+	return c.state.provider
+}
+
 func (c CommandImpl) GetCommitHash(ctx context.Context) (string, error) {
 	return c.cli.Execute(ctx, "rev-parse", "HEAD")
 }
 
+func (c CommandImpl) ResolveCommit(ctx context.Context, rev string) (string, error) {
+	return c.cli.Execute(ctx, "rev-parse", "--verify", "--quiet", rev+"^{commit}")
+}
+
 func (c CommandImpl) Clone(ctx context.Context, repo string) error {
-	_, err := execx.NewCommand(
-		c.cli.Command(),
-		"clone",
-		repo,
-		c.cli.Dir().Tail(),
-	).Execute(ctx, execx.WithDir(c.cli.Dir().Parent().DirPath()), execx.WithEnv(c.cli.Env()))
-	return err
+	var errs []error
+	for _, remote := range append([]string{repo}, c.fallbacks...) {
+		source, err := c.clone(ctx, remote)
+		if err != nil {
+			logx.Error("clone", logx.S("remote", remote), logx.Err(err))
+			errs = append(errs, err)
+			continue
+		}
+		c.state.provider = remote
+		if source == repo {
+			return nil
+		}
+		// point origin to repo for later runs
+		_, err = c.cli.Execute(ctx, "remote", "set-url", "origin", repo)
+		return err
+	}
+	return errors.Join(errs...)
+}
+
+// clone clones remote and returns the actual source.
+func (c CommandImpl) clone(ctx context.Context, remote string) (string, error) {
+	if c.offline {
+		return c.cloneOffline(ctx, remote)
+	}
+	args := []string{"clone"}
+	if reference, ok := c.syncMirror(ctx, remote); ok {
+		args = append(args, "--reference", reference.String(), "--dissociate")
+	}
+	args = append(args, remote, c.cli.Dir().Tail())
+	_, err := c.cli.ExecuteIn(ctx, c.cli.Dir().Parent().DirPath(), args...)
+	return remote, err
+}
+
+func (c CommandImpl) cloneOffline(ctx context.Context, remote string) (string, error) {
+	source := remote
+	if c.mirror != nil {
+		if path, ok := c.mirror.Lookup(remote); ok {
+			source = path.String()
+		}
+	}
+	if source == remote && !isLocal(remote) {
+		return "", errorx.Errorf(ErrOffline, "no mirror of %s", remote)
+	}
+	_, err := c.cli.ExecuteIn(ctx, c.cli.Dir().Parent().DirPath(), "clone", source, c.cli.Dir().Tail())
+	return source, err
+}
+
+// isLocal returns true if repo is available without network, an absolute path or a file:// url.
+func isLocal(repo string) bool {
+	return filepath.IsAbs(repo) || strings.HasPrefix(repo, "file://")
+}
+
+// syncMirror updates the mirror of repo.
+// Returns false if no mirror is available, then clone from repo directly.
+func (c CommandImpl) syncMirror(ctx context.Context, repo string) (filepathx.DirPath, bool) {
+	if c.mirror == nil {
+		return filepathx.DirPath{}, false
+	}
+	path, err := c.mirror.Sync(ctx, repo)
+	if err != nil {
+		logx.Error("ignore mirror", logx.Err(err))
+		return filepathx.DirPath{}, false
+	}
+	return path, true
+}
+
+// tryRemotes calls f with origin and the fallbacks in order until it succeeds.
+func (c CommandImpl) tryRemotes(ctx context.Context, f func(remote string) error) error {
+	var errs []error
+	for _, remote := range append([]string{"origin"}, c.fallbacks...) {
+		if err := f(remote); err != nil {
+			logx.Error("try remote", logx.S("remote", remote), logx.Err(err))
+			errs = append(errs, err)
+			continue
+		}
+		if remote == "origin" {
+			url, err := c.cli.Execute(ctx, "remote", "get-url", "origin")
+			if err != nil {
+				return err
+			}
+			remote = url
+		}
+		c.state.provider = remote
+		return nil
+	}
+	return errors.Join(errs...)
 }
 
 func (c CommandImpl) Fetch(ctx context.Context) error {
-	_, err := c.cli.Execute(ctx, "fetch", "--prune")
-	return err
+	if c.offline {
+		logx.Info("skip fetch because offline")
+		return nil
+	}
+	return c.tryRemotes(ctx, func(remote string) error {
+		args := []string{"fetch", "--prune", remote}
+		if remote != "origin" {
+			// a fallback url has no configured refspec, update the refs of origin as if fetched from origin
+			args = append(args, "+refs/heads/*:refs/remotes/origin/*", "--tags")
+		}
+		_, err := c.cli.Execute(ctx, args...)
+		return err
+	})
 }
 
 func (c CommandImpl) Checkout(ctx context.Context, commit string) error {
@@ -110,6 +277,63 @@ func (c CommandImpl) ResetHard(ctx context.Context, commit string) error {
 }
 
 func (c CommandImpl) PullForce(ctx context.Context, repo string) error {
-	_, err := c.cli.Execute(ctx, "pull", "--prune", "--force", "origin", repo)
-	return err
+	if c.offline {
+		logx.Info("skip pull because offline")
+		return nil
+	}
+	return c.tryRemotes(ctx, func(remote string) error {
+		_, err := c.cli.Execute(ctx, "pull", "--prune", "--force", remote, repo)
+		return err
+	})
+}
+
+func (c CommandImpl) DefaultBranch(ctx context.Context, repo string) (string, error) {
+	if c.offline {
+		return "", errorx.Errorf(ErrOffline, "ls-remote %s", repo)
+	}
+	out, err := c.cli.ExecuteIn(ctx, filepathx.PWD(), "ls-remote", "--symref", repo, "HEAD")
+	if err != nil {
+		return "", err
+	}
+	return ParseSymref(out)
+}
+
+func (c CommandImpl) HasRef(ctx context.Context, repo, ref string) (bool, error) {
+	if c.offline {
+		return false, errorx.Errorf(ErrOffline, "ls-remote %s", repo)
+	}
+	out, err := c.cli.ExecuteIn(ctx, filepathx.PWD(), "ls-remote", "--heads", "--tags", repo, ref)
+	if err != nil {
+		return false, err
+	}
+	for line := range strings.Lines(out) {
+		_, name, _ := strings.Cut(strings.TrimSpace(line), "\t")
+		if name == "refs/heads/"+ref || name == "refs/tags/"+ref {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (c CommandImpl) Log(ctx context.Context, from, to string) (string, error) {
+	return c.cli.Execute(ctx, "log", "--oneline", "--no-decorate", from+".."+to)
+}
+
+func (c CommandImpl) DiffStat(ctx context.Context, from, to string) (string, error) {
+	return c.cli.Execute(ctx, "diff", "--stat", from, to)
+}
+
+// ParseSymref returns the branch of HEAD from the output of ls-remote --symref, like
+//
+//	ref: refs/heads/main	HEAD
+//	0123456789abcdef0123456789abcdef01234567	HEAD
+func ParseSymref(out string) (string, error) {
+	for line := range strings.Lines(out) {
+		ref, name, ok := strings.Cut(strings.TrimSpace(line), "\t")
+		if !ok || name != "HEAD" || !strings.HasPrefix(ref, "ref: ") {
+			continue
+		}
+		return strings.TrimPrefix(strings.TrimPrefix(ref, "ref: "), "refs/heads/"), nil
+	}
+	return "", errorx.Errorf(ErrCLI, "no symref of HEAD")
 }
@@ -0,0 +1,47 @@
+package git_test
+
+import (
+	"berquerant/install-via-git-go/git"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseSymref(t *testing.T) {
+	for _, tc := range []struct {
+		title   string
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{
+			title: "main",
+			input: "ref: refs/heads/main\tHEAD\n0123456789abcdef0123456789abcdef01234567\tHEAD\n",
+			want:  "main",
+		},
+		{
+			title: "slash",
+			input: "ref: refs/heads/release/v1\tHEAD\n0123456789abcdef0123456789abcdef01234567\tHEAD",
+			want:  "release/v1",
+		},
+		{
+			title:   "no symref",
+			input:   "0123456789abcdef0123456789abcdef01234567\tHEAD",
+			wantErr: true,
+		},
+		{
+			title:   "empty",
+			wantErr: true,
+		},
+	} {
+		t.Run(tc.title, func(t *testing.T) {
+			got, err := git.ParseSymref(tc.input)
+			if tc.wantErr {
+				assert.ErrorIs(t, err, git.ErrCLI)
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
@@ -0,0 +1,204 @@
+package history
+
+import (
+	"berquerant/install-via-git-go/errorx"
+	"berquerant/install-via-git-go/filepathx"
+	"berquerant/install-via-git-go/logx"
+	"bufio"
+	"encoding/json"
+	"errors"
+	"os"
+	"strings"
+	"time"
+)
+
+var (
+	ErrHistory = errors.New("History")
+)
+
+// FileName is the history file in workDir.
+const FileName = ".ivg.history"
+
+type Outcome string
+
+const (
+	// OutcomeSuccess means that the lock was committed.
+	OutcomeSuccess Outcome = "success"
+	// OutcomeRollback means that the installation failed and the lock was rolled back.
+	OutcomeRollback Outcome = "rollback"
+	// OutcomeNoop means that the installed commit was up to date, nothing was installed.
+	OutcomeNoop Outcome = "noop"
+	// OutcomeFailure means that setup failed before the strategy, the lock was not changed.
+	OutcomeFailure Outcome = "failure"
+	// OutcomeCanceled means that check failed and canceled the installation, the lock was not changed.
+	OutcomeCanceled Outcome = "canceled"
+)
+
+// StrategyRollback is the strategy of the entries of the rollback subcommand.
+const StrategyRollback = "rollback"
+
+// Duration is a time.Duration written as a string like 1.5s.
+type Duration time.Duration
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
+
+// Entry is a line of the history file.
+type Entry struct {
+	Time     time.Time `json:"time"`
+	URI      string    `json:"uri"`
+	Strategy string    `json:"strategy"`
+	// From is the hash of the lock before the installation.
+	From string `json:"from,omitempty"`
+	// To is the hash the installation aimed at.
+	To       string   `json:"to,omitempty"`
+	Outcome  Outcome  `json:"outcome"`
+	Duration Duration `json:"duration"`
+	Error    string   `json:"error,omitempty"`
+	// Changes are the commits From..To, one line per commit.
+	Changes []string `json:"changes,omitempty"`
+}
+
+// Installed returns true if the entry installed To.
+func (e *Entry) Installed() bool {
+	return e.Outcome == OutcomeSuccess && e.To != ""
+}
+
+// File is an append-only history file, one JSON entry per line.
+type File struct {
+	path filepathx.FilePath
+}
+
+func NewFile(path filepathx.FilePath) *File {
+	return &File{
+		path: path,
+	}
+}
+
+// FromWorkDir returns the history file of workDir.
+func FromWorkDir(workDir filepathx.DirPath) *File {
+	return NewFile(workDir.Join(FileName).FilePath())
+}
+
+func (f *File) Path() filepathx.FilePath {
+	return f.path
+}
+
+// Append writes entry at the end of the file.
+func (f *File) Append(entry *Entry) error {
+	b, err := json.Marshal(entry)
+	if err != nil {
+		return errorx.Errorf(errors.Join(ErrHistory, err), "marshal entry")
+	}
+	file, err := os.OpenFile(f.path.String(), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return errorx.Errorf(errors.Join(ErrHistory, err), "open %s", f.path)
+	}
+	defer file.Close()
+	if _, err := file.Write(append(b, '\n')); err != nil {
+		return errorx.Errorf(errors.Join(ErrHistory, err), "append to %s", f.path)
+	}
+	logx.Debug("append history", logx.S("path", f.path.String()), logx.S("outcome", string(entry.Outcome)))
+	return nil
+}
+
+// Read returns the entries, oldest first.
+// Returns nil if the file does not exist, ignores broken lines.
+func (f *File) Read() ([]*Entry, error) {
+	if !f.path.Exist() {
+		return nil, nil
+	}
+	file, err := f.path.Open()
+	if err != nil {
+		return nil, errorx.Errorf(errors.Join(ErrHistory, err), "open %s", f.path)
+	}
+	defer file.Close()
+
+	var entries []*Entry
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		var entry Entry
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			logx.Debug("ignore broken history", logx.S("line", line), logx.Err(err))
+			continue
+		}
+		entries = append(entries, &entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errorx.Errorf(errors.Join(ErrHistory, err), "read %s", f.path)
+	}
+	return entries, nil
+}
+
+// Filter selects entries, zero values select all.
+type Filter struct {
+	Strategy string
+	Outcome  Outcome
+	Since    time.Time
+	// Limit keeps the latest entries.
+	Limit int
+}
+
+func (f Filter) Apply(entries []*Entry) []*Entry {
+	var result []*Entry
+	for _, entry := range entries {
+		if f.Strategy != "" && entry.Strategy != f.Strategy {
+			continue
+		}
+		if f.Outcome != "" && entry.Outcome != f.Outcome {
+			continue
+		}
+		if !f.Since.IsZero() && entry.Time.Before(f.Since) {
+			continue
+		}
+		result = append(result, entry)
+	}
+	if f.Limit > 0 && len(result) > f.Limit {
+		result = result[len(result)-f.Limit:]
+	}
+	return result
+}
+
+// Previous returns the commit installed steps installations before current.
+// The installed commits are the successful entries except rollbacks, the consecutive same commits are counted once.
+// Counts from the last installation of current if any, otherwise from the latest installation.
+func Previous(entries []*Entry, current string, steps int) (string, bool) {
+	var installed []string
+	for _, entry := range entries {
+		if !entry.Installed() || entry.Strategy == StrategyRollback {
+			continue
+		}
+		if n := len(installed); n > 0 && installed[n-1] == entry.To {
+			continue
+		}
+		installed = append(installed, entry.To)
+	}
+	for i := len(installed) - 1; i >= 0; i-- {
+		if installed[i] == current {
+			installed = installed[:i]
+			break
+		}
+	}
+	if steps < 1 || steps > len(installed) {
+		return "", false
+	}
+	return installed[len(installed)-steps], true
+}
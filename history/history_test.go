@@ -0,0 +1,196 @@
+package history_test
+
+import (
+	"berquerant/install-via-git-go/filepathx"
+	"berquerant/install-via-git-go/history"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFile(t *testing.T) {
+	dir, err := filepathx.NewPath(t.TempDir())
+	if !assert.Nil(t, err) {
+		return
+	}
+	file := history.FromWorkDir(dir.DirPath())
+
+	t.Run("empty", func(t *testing.T) {
+		got, err := file.Read()
+		assert.Nil(t, err)
+		assert.Len(t, got, 0)
+	})
+
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	entries := []*history.Entry{
+		{
+			Time:     now,
+			URI:      "https://example.com/repo.git",
+			Strategy: "TinitFromEmpty",
+			To:       "hash1",
+			Outcome:  history.OutcomeSuccess,
+			Duration: history.Duration(1500 * time.Millisecond),
+		},
+		{
+			Time:     now.Add(time.Hour),
+			URI:      "https://example.com/repo.git",
+			Strategy: "TupdateToLatestWithLock",
+			From:     "hash1",
+			To:       "hash2",
+			Outcome:  history.OutcomeRollback,
+			Duration: history.Duration(time.Second),
+			Error:    "run install",
+		},
+		{
+			Time:     now.Add(2 * time.Hour),
+			URI:      "https://example.com/repo.git",
+			Strategy: "TupdateToLatestWithLock",
+			From:     "hash1",
+			To:       "hash3",
+			Outcome:  history.OutcomeSuccess,
+			Duration: history.Duration(2 * time.Second),
+		},
+	}
+	for _, entry := range entries {
+		if !assert.Nil(t, file.Append(entry)) {
+			return
+		}
+	}
+
+	t.Run("read", func(t *testing.T) {
+		got, err := file.Read()
+		assert.Nil(t, err)
+		assert.Equal(t, entries, got)
+	})
+
+	t.Run("broken line", func(t *testing.T) {
+		f, err := os.OpenFile(file.Path().String(), os.O_APPEND|os.O_WRONLY, 0644)
+		if !assert.Nil(t, err) {
+			return
+		}
+		_, err = f.WriteString(`{"time":`)
+		assert.Nil(t, err)
+		assert.Nil(t, f.Close())
+
+		got, err := file.Read()
+		assert.Nil(t, err)
+		assert.Equal(t, entries, got)
+	})
+}
+
+func TestFilter(t *testing.T) {
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	entries := []*history.Entry{
+		{Time: now, Strategy: "TinitFromEmpty", Outcome: history.OutcomeSuccess},
+		{Time: now.Add(time.Hour), Strategy: "TupdateToLatestWithLock", Outcome: history.OutcomeRollback},
+		{Time: now.Add(2 * time.Hour), Strategy: "TupdateToLatestWithLock", Outcome: history.OutcomeSuccess},
+	}
+
+	for _, tc := range []struct {
+		title  string
+		filter history.Filter
+		want   []*history.Entry
+	}{
+		{
+			title: "all",
+			want:  entries,
+		},
+		{
+			title:  "strategy",
+			filter: history.Filter{Strategy: "TupdateToLatestWithLock"},
+			want:   entries[1:],
+		},
+		{
+			title:  "outcome",
+			filter: history.Filter{Outcome: history.OutcomeSuccess},
+			want:   []*history.Entry{entries[0], entries[2]},
+		},
+		{
+			title:  "since",
+			filter: history.Filter{Since: now.Add(30 * time.Minute)},
+			want:   entries[1:],
+		},
+		{
+			title:  "limit",
+			filter: history.Filter{Limit: 1},
+			want:   entries[2:],
+		},
+	} {
+		t.Run(tc.title, func(t *testing.T) {
+			assert.Equal(t, tc.want, tc.filter.Apply(entries))
+		})
+	}
+}
+
+func TestPrevious(t *testing.T) {
+	entries := []*history.Entry{
+		{To: "a", Outcome: history.OutcomeSuccess},
+		{To: "b", Outcome: history.OutcomeSuccess},
+		{To: "c", Outcome: history.OutcomeRollback},
+		{Outcome: history.OutcomeSuccess},
+		{To: "b", Outcome: history.OutcomeSuccess},
+		{To: "c", Outcome: history.OutcomeFailure},
+		{To: "d", Outcome: history.OutcomeSuccess},
+		{To: "e", Outcome: history.OutcomeNoop},
+		{To: "b", Strategy: history.StrategyRollback, Outcome: history.OutcomeSuccess},
+	}
+
+	for _, tc := range []struct {
+		title   string
+		current string
+		steps   int
+		want    string
+		wantOK  bool
+	}{
+		{
+			title:   "previous",
+			current: "d",
+			steps:   1,
+			want:    "b",
+			wantOK:  true,
+		},
+		{
+			title:   "steps",
+			current: "d",
+			steps:   2,
+			want:    "a",
+			wantOK:  true,
+		},
+		{
+			title:   "too many steps",
+			current: "d",
+			steps:   3,
+		},
+		{
+			title:   "current not installed",
+			current: "x",
+			steps:   1,
+			want:    "d",
+			wantOK:  true,
+		},
+		{
+			title:   "zero steps",
+			current: "d",
+		},
+		{
+			title:   "after rollback",
+			current: "b",
+			steps:   1,
+			want:    "a",
+			wantOK:  true,
+		},
+		{
+			title:   "too many steps after rollback",
+			current: "b",
+			steps:   2,
+		},
+	} {
+		t.Run(tc.title, func(t *testing.T) {
+			got, ok := history.Previous(entries, tc.current, tc.steps)
+			assert.Equal(t, tc.wantOK, ok)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
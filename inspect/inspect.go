@@ -3,6 +3,8 @@ package inspect
 import (
 	"berquerant/install-via-git-go/filepathx"
 	"berquerant/install-via-git-go/git"
+	"berquerant/install-via-git-go/lock"
+	"berquerant/install-via-git-go/logx"
 	"berquerant/install-via-git-go/strategy"
 	"context"
 )
@@ -11,7 +13,7 @@ func RepoStatus(ctx context.Context, command git.Command, lockFile filepathx.Fil
 	if !lockFile.Exist() {
 		return strategy.RSunknown
 	}
-	commit, err := lockFile.Read()
+	content, err := lockFile.Read()
 	if err != nil {
 		return strategy.RSunknown
 	}
@@ -19,7 +21,14 @@ func RepoStatus(ctx context.Context, command git.Command, lockFile filepathx.Fil
 	if err != nil {
 		return strategy.RSunknown
 	}
-	if current == commit {
+	hash := lock.ParseContent(content).Hash
+	if hash == "" {
+		return strategy.RSconflict
+	}
+	if resolved, err := command.ResolveCommit(ctx, hash); err == nil {
+		hash = resolved
+	}
+	if current == hash {
 		return strategy.RSmatch
 	}
 	return strategy.RSconflict
@@ -50,14 +59,34 @@ func (us UpdateSpec) Get() strategy.UpdateSpec {
 	}
 }
 
-func LockExistence(lockFile filepathx.FilePath) strategy.LockExistence {
+// LockExistence returns LEexist if the lock file has a full commit hash,
+// or a value the repo resolves into a commit, like an abbreviated hash, a tag or a branch.
+// If the repo does not exist yet, the value is trusted because it cannot be resolved.
+func LockExistence(ctx context.Context, command git.Command, lockFile filepathx.FilePath) strategy.LockExistence {
 	if !lockFile.Exist() {
 		return strategy.LEnone
 	}
 	content, err := lockFile.Read()
-	if err != nil || content == "" {
+	if err != nil {
+		return strategy.LEnone
+	}
+	hash := lock.ParseContent(content).Hash
+	if hash == "" {
+		return strategy.LEnone
+	}
+	if lock.IsHash(hash) {
+		return strategy.LEexist
+	}
+	if RepoExistence(ctx, command) == strategy.REnone {
+		return strategy.LEexist
+	}
+	resolved, err := command.ResolveCommit(ctx, hash)
+	if err != nil {
+		// truncated, broken or unknown, do not trust
+		logx.Error("invalid lock", logx.S("path", lockFile.String()), logx.S("hash", hash), logx.Err(err))
 		return strategy.LEnone
 	}
+	logx.Info("resolve lock", logx.S("hash", hash), logx.S("resolved", resolved))
 	return strategy.LEexist
 }
 
@@ -0,0 +1,147 @@
+package inspect_test
+
+import (
+	"berquerant/install-via-git-go/execx"
+	"berquerant/install-via-git-go/filepathx"
+	"berquerant/install-via-git-go/git"
+	"berquerant/install-via-git-go/inspect"
+	"berquerant/install-via-git-go/strategy"
+	"context"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLock(t *testing.T) {
+	base, err := filepathx.NewPath(t.TempDir())
+	if !assert.Nil(t, err) {
+		return
+	}
+	repo := base.Join("repo").DirPath()
+	if !assert.Nil(t, repo.Ensure()) {
+		return
+	}
+	env := execx.EnvFromSlice([]string{
+		"GIT_AUTHOR_NAME=ivg",
+		"GIT_AUTHOR_EMAIL=ivg@example.com",
+		"GIT_COMMITTER_NAME=ivg",
+		"GIT_COMMITTER_EMAIL=ivg@example.com",
+	})
+	r, err := execx.NewExecutorFromStrings([]string{
+		"git init -q -b main",
+		"echo 1 > f && git add f && git commit -q -m 1",
+		"git tag v1",
+		"git rev-parse HEAD",
+		"echo 2 > f && git commit -q -am 2",
+		"git tag v2",
+		"git rev-parse HEAD",
+	}, "bash").Execute(context.TODO(), execx.WithDir(repo), execx.WithEnv(env))
+	if !assert.Nil(t, err) {
+		return
+	}
+	hashes := strings.Fields(r.Stdout)
+	if !assert.Len(t, hashes, 2) {
+		return
+	}
+	first, head := hashes[0], hashes[1]
+
+	ctx := context.TODO()
+	command := git.NewCommand(git.NewCLI(repo, execx.NewEnv(), "git"))
+	missingRepo := git.NewCommand(git.NewCLI(base.Join("missing").DirPath(), execx.NewEnv(), "git"))
+
+	for _, tc := range []struct {
+		title          string
+		lock           *string
+		command        git.Command
+		wantExistence  strategy.LockExistence
+		wantRepoStatus strategy.RepoStatus
+	}{
+		{
+			title:          "full hash",
+			lock:           &head,
+			command:        command,
+			wantExistence:  strategy.LEexist,
+			wantRepoStatus: strategy.RSmatch,
+		},
+		{
+			title:          "abbreviated hash",
+			lock:           ptr(head[:7]),
+			command:        command,
+			wantExistence:  strategy.LEexist,
+			wantRepoStatus: strategy.RSmatch,
+		},
+		{
+			title:          "abbreviated hash not checked out",
+			lock:           ptr(first[:7]),
+			command:        command,
+			wantExistence:  strategy.LEexist,
+			wantRepoStatus: strategy.RSconflict,
+		},
+		{
+			title:          "tag",
+			lock:           ptr("v2"),
+			command:        command,
+			wantExistence:  strategy.LEexist,
+			wantRepoStatus: strategy.RSmatch,
+		},
+		{
+			title:          "tag not checked out",
+			lock:           ptr("v1"),
+			command:        command,
+			wantExistence:  strategy.LEexist,
+			wantRepoStatus: strategy.RSconflict,
+		},
+		{
+			title:          "unknown ref",
+			lock:           ptr("unknown"),
+			command:        command,
+			wantExistence:  strategy.LEnone,
+			wantRepoStatus: strategy.RSconflict,
+		},
+		{
+			title:          "truncated hash",
+			lock:           ptr(head[:7] + "zz"),
+			command:        command,
+			wantExistence:  strategy.LEnone,
+			wantRepoStatus: strategy.RSconflict,
+		},
+		{
+			title:          "empty lock",
+			lock:           ptr(""),
+			command:        command,
+			wantExistence:  strategy.LEnone,
+			wantRepoStatus: strategy.RSconflict,
+		},
+		{
+			title:          "no lock",
+			command:        command,
+			wantExistence:  strategy.LEnone,
+			wantRepoStatus: strategy.RSunknown,
+		},
+		{
+			title:          "missing repo",
+			lock:           ptr(head[:7]),
+			command:        missingRepo,
+			wantExistence:  strategy.LEexist,
+			wantRepoStatus: strategy.RSunknown,
+		},
+	} {
+		t.Run(tc.title, func(t *testing.T) {
+			lockFile := base.Join("lock").FilePath()
+			_ = os.Remove(lockFile.String())
+			if tc.lock != nil {
+				if !assert.Nil(t, lockFile.Write(*tc.lock)) {
+					return
+				}
+			}
+			assert.Equal(t, tc.wantExistence, inspect.LockExistence(ctx, tc.command, lockFile))
+			assert.Equal(t, tc.wantRepoStatus, inspect.RepoStatus(ctx, tc.command, lockFile))
+		})
+	}
+}
+
+func ptr(s string) *string {
+	return &s
+}
@@ -0,0 +1,115 @@
+package journal
+
+import (
+	"berquerant/install-via-git-go/errorx"
+	"berquerant/install-via-git-go/filepathx"
+	"berquerant/install-via-git-go/logx"
+	"encoding/json"
+	"errors"
+	"os"
+	"time"
+)
+
+var (
+	ErrJournal = errors.New("Journal")
+)
+
+// FileName is the journal file in workDir.
+const FileName = ".ivg.journal"
+
+type Phase string
+
+const (
+	// PhaseSetup means that the backups are created, check and setup are running.
+	PhaseSetup Phase = "setup"
+	// PhaseStrategy means that the strategy is changing the repo.
+	PhaseStrategy Phase = "strategy"
+	// PhaseInstall means that install is running.
+	PhaseInstall Phase = "install"
+	// PhaseCommit means that the lock is being written.
+	PhaseCommit Phase = "commit"
+	// PhaseRollback means that the run is rolling back.
+	PhaseRollback Phase = "rollback"
+)
+
+// Backup is a backup created by the run.
+type Backup struct {
+	// Dir is the directory of the backup.
+	Dir string `json:"dir"`
+	// Origin is the path backed up.
+	Origin string `json:"origin"`
+	// Format is the format of the backup, copy if empty.
+	Format string `json:"format,omitempty"`
+}
+
+// Journal is the state of a run, removed when the run ends.
+// The journal left means that the run was interrupted.
+type Journal struct {
+	PID     int       `json:"pid"`
+	Started time.Time `json:"started"`
+	Phase   Phase     `json:"phase"`
+	// Lock is the content of the lock file before the run.
+	Lock    string   `json:"lock"`
+	Backups []Backup `json:"backups,omitempty"`
+	// Next is the commit to be installed.
+	Next string `json:"next,omitempty"`
+	// Staged is the worktree of Next, added by the run.
+	Staged string `json:"staged,omitempty"`
+}
+
+type File struct {
+	path filepathx.FilePath
+}
+
+func NewFile(path filepathx.FilePath) *File {
+	return &File{
+		path: path,
+	}
+}
+
+// FromWorkDir returns the journal file of workDir.
+func FromWorkDir(workDir filepathx.DirPath) *File {
+	return NewFile(workDir.Join(FileName).FilePath())
+}
+
+func (f *File) Path() filepathx.FilePath {
+	return f.path
+}
+
+// Write replaces the journal atomically.
+func (f *File) Write(j *Journal) error {
+	b, err := json.Marshal(j)
+	if err != nil {
+		return errorx.Errorf(errors.Join(ErrJournal, err), "marshal")
+	}
+	if err := f.path.WriteAtomic(string(b)); err != nil {
+		return errorx.Errorf(errors.Join(ErrJournal, err), "write %s", f.path)
+	}
+	logx.Debug("write journal", logx.S("path", f.path.String()), logx.S("phase", string(j.Phase)))
+	return nil
+}
+
+// Read returns the journal, false if not exist.
+func (f *File) Read() (*Journal, bool, error) {
+	b, err := os.ReadFile(f.path.String())
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, errorx.Errorf(errors.Join(ErrJournal, err), "read %s", f.path)
+	}
+	var j Journal
+	if err := json.Unmarshal(b, &j); err != nil {
+		return nil, false, errorx.Errorf(errors.Join(ErrJournal, err), "parse %s", f.path)
+	}
+	return &j, true, nil
+}
+
+// Remove removes the journal, no error if not exist.
+func (f *File) Remove() error {
+	if err := os.Remove(f.path.String()); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return errorx.Errorf(errors.Join(ErrJournal, err), "remove %s", f.path)
+	}
+	logx.Debug("remove journal", logx.S("path", f.path.String()))
+	return nil
+}
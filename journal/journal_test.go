@@ -0,0 +1,56 @@
+package journal_test
+
+import (
+	"berquerant/install-via-git-go/filepathx"
+	"berquerant/install-via-git-go/journal"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFile(t *testing.T) {
+	dir, err := filepathx.NewPath(t.TempDir())
+	if !assert.Nil(t, err) {
+		return
+	}
+	file := journal.FromWorkDir(dir.DirPath())
+
+	_, ok, err := file.Read()
+	assert.Nil(t, err)
+	assert.False(t, ok)
+	assert.Nil(t, file.Remove())
+
+	j := &journal.Journal{
+		PID:     1,
+		Started: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		Phase:   journal.PhaseSetup,
+		Lock:    "hash\nbranch=main",
+		Backups: []journal.Backup{{Dir: "/tmp/backup", Origin: "/work/lock"}},
+	}
+	if !assert.Nil(t, file.Write(j)) {
+		return
+	}
+	j.Phase = journal.PhaseInstall
+	j.Next = "next"
+	if !assert.Nil(t, file.Write(j)) {
+		return
+	}
+
+	got, ok, err := file.Read()
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, j, got)
+
+	assert.Nil(t, file.Remove())
+	_, ok, err = file.Read()
+	assert.Nil(t, err)
+	assert.False(t, ok)
+
+	t.Run("broken", func(t *testing.T) {
+		assert.Nil(t, os.WriteFile(file.Path().String(), []byte("{"), 0600))
+		_, _, err := file.Read()
+		assert.ErrorIs(t, err, journal.ErrJournal)
+	})
+}
@@ -0,0 +1,67 @@
+package lock
+
+import (
+	"strings"
+)
+
+// Content is the content of the lock file.
+//
+// The first line is the commit hash,
+// the following lines are the metadata in key=value form.
+type Content struct {
+	Hash string
+	// Remote is the remote which provided the commit.
+	Remote string
+	// Branch is the branch detected from the remote HEAD.
+	Branch string
+}
+
+const (
+	metaRemote = "remote"
+	metaBranch = "branch"
+)
+
+// IsHash returns true if s looks like a full commit hash, SHA-1 or SHA-256.
+func IsHash(s string) bool {
+	if len(s) != 40 && len(s) != 64 {
+		return false
+	}
+	for _, c := range s {
+		if !('0' <= c && c <= '9' || 'a' <= c && c <= 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+func ParseContent(s string) Content {
+	lines := strings.Split(strings.TrimSpace(s), "\n")
+	c := Content{
+		Hash: strings.TrimSpace(lines[0]),
+	}
+	for _, line := range lines[1:] {
+		key, value, ok := strings.Cut(strings.TrimSpace(line), "=")
+		if !ok {
+			continue
+		}
+		switch key {
+		case metaRemote:
+			c.Remote = value
+		case metaBranch:
+			c.Branch = value
+		}
+	}
+	return c
+}
+
+func (c Content) String() string {
+	var b strings.Builder
+	b.WriteString(c.Hash)
+	if c.Remote != "" {
+		b.WriteString("\n" + metaRemote + "=" + c.Remote)
+	}
+	if c.Branch != "" {
+		b.WriteString("\n" + metaBranch + "=" + c.Branch)
+	}
+	return b.String()
+}
@@ -0,0 +1,78 @@
+package lock_test
+
+import (
+	"berquerant/install-via-git-go/lock"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestContent(t *testing.T) {
+	for _, tc := range []struct {
+		title   string
+		input   string
+		want    lock.Content
+		written string
+	}{
+		{
+			title: "empty",
+		},
+		{
+			title:   "hash",
+			input:   "hash\n",
+			want:    lock.Content{Hash: "hash"},
+			written: "hash",
+		},
+		{
+			title: "remote",
+			input: "hash\nremote=https://example.com/repo.git\n",
+			want: lock.Content{
+				Hash:   "hash",
+				Remote: "https://example.com/repo.git",
+			},
+			written: "hash\nremote=https://example.com/repo.git",
+		},
+		{
+			title: "branch",
+			input: "hash\nremote=https://example.com/repo.git\nbranch=master\n",
+			want: lock.Content{
+				Hash:   "hash",
+				Remote: "https://example.com/repo.git",
+				Branch: "master",
+			},
+			written: "hash\nremote=https://example.com/repo.git\nbranch=master",
+		},
+		{
+			title:   "ignore unknown",
+			input:   "hash\nunknown=value\ninvalid",
+			want:    lock.Content{Hash: "hash"},
+			written: "hash",
+		},
+	} {
+		t.Run(tc.title, func(t *testing.T) {
+			got := lock.ParseContent(tc.input)
+			assert.Equal(t, tc.want, got)
+			assert.Equal(t, tc.written, got.String())
+		})
+	}
+}
+
+func TestIsHash(t *testing.T) {
+	for _, tc := range []struct {
+		title string
+		input string
+		want  bool
+	}{
+		{title: "empty"},
+		{title: "sha1", input: "d56688b1455cfd830e1227f071d20373c80a2931", want: true},
+		{title: "sha256", input: "d56688b1455cfd830e1227f071d20373c80a2931d56688b1455cfd830e1227f0", want: true},
+		{title: "truncated", input: "d56688b1455cfd830e1227f071d2"},
+		{title: "upper", input: "D56688B1455CFD830E1227F071D20373C80A2931"},
+		{title: "not hex", input: "z56688b1455cfd830e1227f071d20373c80a2931"},
+		{title: "ref", input: "main"},
+	} {
+		t.Run(tc.title, func(t *testing.T) {
+			assert.Equal(t, tc.want, lock.IsHash(tc.input))
+		})
+	}
+}
@@ -4,12 +4,17 @@ import (
 	"berquerant/install-via-git-go/errorx"
 	"berquerant/install-via-git-go/filepathx"
 	"berquerant/install-via-git-go/logx"
-	"strings"
 )
 
 type Pair struct {
 	Current string
 	Next    string
+	// Remote is the remote which provided Next.
+	// Recorded into the lock file if not empty.
+	Remote string
+	// Branch is the branch detected from the remote HEAD.
+	// Recorded into the lock file if not empty.
+	Branch string
 }
 
 // Keeper manages commit hashes.
@@ -28,21 +33,25 @@ func NewFileKeeper(path filepathx.FilePath) *FileKeeper {
 		path: path,
 	}
 	current, err := path.Read()
-	current = strings.TrimSpace(current)
+	content := ParseContent(current)
 	logx.Debug("keeper new",
 		logx.S("path", path.String()),
-		logx.S("current", current),
+		logx.S("current", content.Hash),
+		logx.S("remote", content.Remote),
+		logx.S("branch", content.Branch),
 		logx.Err(err),
 	)
 	if err == nil {
-		k.pair.Current = current
+		k.pair.Current = content.Hash
+		k.current = content
 	}
 	return k
 }
 
 type FileKeeper struct {
-	pair Pair
-	path filepathx.FilePath
+	pair    Pair
+	path    filepathx.FilePath
+	current Content
 }
 
 func (f *FileKeeper) Pair() *Pair {
@@ -51,7 +60,7 @@ func (f *FileKeeper) Pair() *Pair {
 
 func (f *FileKeeper) Clear() error {
 	logx.Debug("keeper clear")
-	if err := f.path.Write(""); err != nil {
+	if err := f.path.WriteAtomic(""); err != nil {
 		return errorx.Errorf(err, "clear %s", f.path)
 	}
 	return nil
@@ -65,7 +74,12 @@ func (f *FileKeeper) Commit() error {
 		return nil
 	}
 
-	if err := f.path.Write(f.pair.Next); err != nil {
+	content := Content{
+		Hash:   f.pair.Next,
+		Remote: f.pair.Remote,
+		Branch: f.pair.Branch,
+	}
+	if err := f.path.WriteAtomic(content.String()); err != nil {
 		return errorx.Errorf(err, "commit %s into %s", f.pair.Next, f.path)
 	}
 	return nil
@@ -79,7 +93,14 @@ func (f *FileKeeper) Rollback() error {
 		return nil
 	}
 
-	if err := f.path.Write(f.pair.Current); err != nil {
+	content := Content{
+		Hash: f.pair.Current,
+	}
+	if f.current.Hash == f.pair.Current {
+		// keep the metadata
+		content = f.current
+	}
+	if err := f.path.WriteAtomic(content.String()); err != nil {
 		return errorx.Errorf(err, "rollback %s into %s", f.pair.Current, f.path)
 	}
 	return nil
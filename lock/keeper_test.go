@@ -69,6 +69,30 @@ func TestFileKeeper(t *testing.T) {
 		})
 	}
 
+	t.Run("Remote", func(t *testing.T) {
+		path := p.Join("remote").FilePath()
+		assert.Nil(t, path.Ensure())
+		defer path.Remove()
+		assert.Nil(t, path.Write("init\nremote=mirror1"))
+
+		k := lock.NewFileKeeper(path)
+		assert.Equal(t, "init", k.Pair().Current)
+		k.Pair().Next = "next"
+		k.Pair().Remote = "mirror2"
+		{
+			assert.Nil(t, k.Commit())
+			got, err := path.Read()
+			assert.Nil(t, err)
+			assert.Equal(t, "next\nremote=mirror2", got)
+		}
+		{
+			assert.Nil(t, k.Rollback())
+			got, err := path.Read()
+			assert.Nil(t, err)
+			assert.Equal(t, "init\nremote=mirror1", got)
+		}
+	})
+
 	t.Run("Clear", func(t *testing.T) {
 		path := p.Join("clear").FilePath()
 		assert.Nil(t, path.Ensure())
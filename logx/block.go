@@ -7,7 +7,7 @@ import (
 )
 
 func setupBlock(debug bool) Logger {
-	blockHandler := NewBlockHandler(os.Stdout)
+	blockHandler := NewRedactHandler(NewBlockHandler(os.Stdout))
 	level := func() slog.Level {
 		if debug {
 			return slog.LevelDebug
@@ -0,0 +1,153 @@
+package logx
+
+import (
+	"context"
+	"path"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+
+	"golang.org/x/exp/slog"
+)
+
+const (
+	// Redacted replaces the secrets.
+	Redacted = "********"
+	// minSecretLength is the minimum length of the values masked everywhere,
+	// to avoid masking short common strings.
+	minSecretLength = 4
+)
+
+// DefaultSecretKeys are the patterns of the keys whose values are secrets.
+var DefaultSecretKeys = []string{
+	"*_TOKEN",
+	"*_PASSWORD",
+	"*_SECRET",
+}
+
+type secretSet struct {
+	mux    sync.RWMutex
+	values []string
+	keys   []string
+}
+
+func (s *secretSet) add(values ...string) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	for _, v := range values {
+		if len(v) >= minSecretLength && !slices.Contains(s.values, v) {
+			s.values = append(s.values, v)
+		}
+	}
+	// mask longer values first, they may contain shorter ones
+	sort.SliceStable(s.values, func(i, j int) bool {
+		return len(s.values[i]) > len(s.values[j])
+	})
+}
+
+func (s *secretSet) addKeys(patterns ...string) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	s.keys = append(s.keys, patterns...)
+}
+
+func (s *secretSet) isKey(key string) bool {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+	for _, p := range s.keys {
+		if ok, _ := path.Match(p, key); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *secretSet) mask(str string) string {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+	for _, v := range s.values {
+		str = strings.ReplaceAll(str, v, Redacted)
+	}
+	return str
+}
+
+var secrets = &secretSet{
+	keys: DefaultSecretKeys,
+}
+
+// AddSecrets registers the values to be masked in the logs.
+// Values shorter than 4 bytes are ignored.
+func AddSecrets(values ...string) {
+	secrets.add(values...)
+}
+
+// AddSecretKeys registers the patterns of the keys whose values are secrets, e.g. *_TOKEN.
+// Pattern syntax is the same as path.Match.
+func AddSecretKeys(patterns ...string) {
+	secrets.addKeys(patterns...)
+}
+
+// IsSecretKey returns true if the value of key is a secret.
+func IsSecretKey(key string) bool {
+	return secrets.isKey(key)
+}
+
+// AddSecretEnv registers the values of the secret keys in env, KEY=VALUE list.
+func AddSecretEnv(env []string) {
+	for _, x := range env {
+		key, value, ok := strings.Cut(x, "=")
+		if ok && IsSecretKey(key) {
+			AddSecrets(value)
+		}
+	}
+}
+
+// Redact masks the secrets in str.
+func Redact(str string) string {
+	return secrets.mask(str)
+}
+
+// RedactHandler masks the secrets in the message and the attributes.
+// Values of the attributes whose keys are secret keys are masked entirely.
+type RedactHandler struct {
+	handler slog.Handler
+}
+
+func NewRedactHandler(handler slog.Handler) *RedactHandler {
+	return &RedactHandler{
+		handler: handler,
+	}
+}
+
+func (h *RedactHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.handler.Enabled(ctx, level)
+}
+
+func (h *RedactHandler) Handle(ctx context.Context, r slog.Record) error {
+	x := slog.NewRecord(r.Time, r.Level, Redact(r.Message), r.PC)
+	r.Attrs(func(attr slog.Attr) bool {
+		x.AddAttrs(redactAttr(attr))
+		return true
+	})
+	return h.handler.Handle(ctx, x)
+}
+
+func (h *RedactHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	xs := make([]slog.Attr, len(attrs))
+	for i, attr := range attrs {
+		xs[i] = redactAttr(attr)
+	}
+	return NewRedactHandler(h.handler.WithAttrs(xs))
+}
+
+func redactAttr(attr slog.Attr) slog.Attr {
+	if IsSecretKey(attr.Key) {
+		return slog.String(attr.Key, Redacted)
+	}
+	return slog.String(attr.Key, Redact(attr.Value.String()))
+}
+
+func (h *RedactHandler) WithGroup(name string) slog.Handler {
+	return NewRedactHandler(h.handler.WithGroup(name))
+}
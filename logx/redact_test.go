@@ -0,0 +1,84 @@
+package logx_test
+
+import (
+	"berquerant/install-via-git-go/logx"
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/exp/slog"
+)
+
+func TestRedact(t *testing.T) {
+	logx.AddSecretKeys("MY_*")
+	logx.AddSecrets("s3cr3t")
+	logx.AddSecretEnv([]string{
+		"GITHUB_TOKEN=ghp_xxxx",
+		"MY_PASS=hunter2",
+		"SHORT_TOKEN=abc",
+		"PUBLIC=visible",
+	})
+
+	t.Run("IsSecretKey", func(t *testing.T) {
+		for _, tc := range []struct {
+			key  string
+			want bool
+		}{
+			{key: "GITHUB_TOKEN", want: true},
+			{key: "DB_PASSWORD", want: true},
+			{key: "APP_SECRET", want: true},
+			{key: "MY_PASS", want: true},
+			{key: "PUBLIC"},
+			{key: "TOKEN"},
+		} {
+			assert.Equal(t, tc.want, logx.IsSecretKey(tc.key), tc.key)
+		}
+	})
+
+	t.Run("Redact", func(t *testing.T) {
+		for _, tc := range []struct {
+			input string
+			want  string
+		}{
+			{
+				input: "s3cr3t",
+				want:  "********",
+			},
+			{
+				input: "echo ghp_xxxx hunter2 abc visible",
+				want:  "echo ******** ******** abc visible",
+			},
+		} {
+			assert.Equal(t, tc.want, logx.Redact(tc.input))
+		}
+	})
+
+	t.Run("RedactHandler", func(t *testing.T) {
+		var buf bytes.Buffer
+		logger := slog.New(logx.NewRedactHandler(logx.NewBlockHandler(&buf)))
+		logger.LogAttrs(context.TODO(), slog.LevelInfo, "msg s3cr3t",
+			slog.String("DB_PASSWORD", "pw"),
+			slog.Any("env", []string{"GITHUB_TOKEN=ghp_xxxx", "PUBLIC=visible"}),
+		)
+		got := buf.String()
+		assert.Contains(t, got, "msg ********")
+		assert.Contains(t, got, "DB_PASSWORD=********")
+		assert.Contains(t, got, "env=[GITHUB_TOKEN=******** PUBLIC=visible]")
+		assert.NotContains(t, got, "s3cr3t")
+		assert.NotContains(t, got, "ghp_xxxx")
+	})
+
+	t.Run("RedactHandlerWithAttrs", func(t *testing.T) {
+		var buf bytes.Buffer
+		logger := slog.New(logx.NewRedactHandler(slog.NewTextHandler(&buf, nil))).With(
+			slog.String("DB_PASSWORD", "pw"),
+			slog.String("cmd", "echo s3cr3t"),
+		)
+		logger.Info("msg")
+		got := buf.String()
+		assert.Contains(t, got, "DB_PASSWORD=********")
+		assert.Contains(t, got, `cmd="echo ********"`)
+		assert.NotContains(t, got, "s3cr3t")
+	})
+}
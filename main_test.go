@@ -1,6 +1,7 @@
 package main
 
 import (
+	"berquerant/install-via-git-go/lock"
 	"fmt"
 	"io"
 	"os"
@@ -154,12 +155,13 @@ install:
 		return
 	}
 
-	lock, err := os.Open(filepath.Join(workDir, lockFile))
+	lockF, err := os.Open(filepath.Join(workDir, lockFile))
 	fail(t, err)
-	defer lock.Close()
-	gotCommit, err := io.ReadAll(lock)
+	defer lockF.Close()
+	gotLock, err := io.ReadAll(lockF)
 	fail(t, err)
-	assert.Equal(t, arg.commit, string(gotCommit))
+	// the lock may also record the branch and the remote
+	assert.Equal(t, arg.commit, lock.ParseContent(string(gotLock)).Hash)
 }
 
 func TestEndToEnd(t *testing.T) {
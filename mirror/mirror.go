@@ -0,0 +1,200 @@
+package mirror
+
+import (
+	"berquerant/install-via-git-go/errorx"
+	"berquerant/install-via-git-go/execx"
+	"berquerant/install-via-git-go/filepathx"
+	"berquerant/install-via-git-go/logx"
+	"berquerant/install-via-git-go/proclock"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+var (
+	ErrMirror = errors.New("Mirror")
+)
+
+// DefaultDir returns the default mirror cache directory, ~/.cache/install-via-git/mirrors.
+func DefaultDir() (filepathx.DirPath, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return filepathx.DirPath{}, errors.Join(ErrMirror, err)
+	}
+	p, err := filepathx.NewPath(filepath.Join(dir, "install-via-git", "mirrors"))
+	if err != nil {
+		return filepathx.DirPath{}, errors.Join(ErrMirror, err)
+	}
+	return p.DirPath(), nil
+}
+
+// Cache is a set of bare mirror repositories shared across work dirs.
+type Cache struct {
+	dir     filepathx.DirPath
+	env     execx.Env
+	command string
+}
+
+func NewCache(dir filepathx.DirPath, env execx.Env, command string) *Cache {
+	return &Cache{
+		dir:     dir,
+		env:     env,
+		command: command,
+	}
+}
+
+func (c *Cache) Dir() filepathx.DirPath {
+	return c.dir
+}
+
+// Path returns the mirror repository of uri.
+func (c *Cache) Path(uri string) filepathx.DirPath {
+	sum := sha256.Sum256([]byte(uri))
+	return c.dir.Join(hex.EncodeToString(sum[:]) + ".git").DirPath()
+}
+
+// lockPath returns the lock of the mirror repository path.
+func (c *Cache) lockPath(path filepathx.DirPath) filepathx.FilePath {
+	return c.dir.Join(strings.TrimSuffix(path.Tail(), ".git") + ".lock").FilePath()
+}
+
+// Sync creates or updates the mirror repository of uri.
+// The mirror is locked against the other processes while syncing.
+func (c *Cache) Sync(ctx context.Context, uri string) (filepathx.DirPath, error) {
+	path := c.Path(uri)
+	logx.Info("sync mirror", logx.S("uri", uri), logx.S("path", path.String()))
+	if err := c.dir.Ensure(); err != nil {
+		return filepathx.DirPath{}, errorx.Errorf(errors.Join(ErrMirror, err), "sync %s", uri)
+	}
+	lock, err := proclock.Acquire(ctx, c.lockPath(path), true, 0)
+	if err != nil {
+		return filepathx.DirPath{}, errorx.Errorf(errors.Join(ErrMirror, err), "sync %s", uri)
+	}
+	defer func() {
+		_ = lock.Release()
+	}()
+	if err := c.sync(ctx, uri, path); err != nil {
+		return filepathx.DirPath{}, errorx.Errorf(errors.Join(ErrMirror, err), "sync %s", uri)
+	}
+	if err := path.Touch(); err != nil {
+		logx.Debug("touch mirror", logx.S("path", path.String()), logx.Err(err))
+	}
+	return path, nil
+}
+
+// Lookup returns the mirror repository of uri if it exists, without network access.
+func (c *Cache) Lookup(uri string) (filepathx.DirPath, bool) {
+	path := c.Path(uri)
+	if !path.Exist() {
+		return filepathx.DirPath{}, false
+	}
+	if err := path.Touch(); err != nil {
+		logx.Debug("touch mirror", logx.S("path", path.String()), logx.Err(err))
+	}
+	return path, true
+}
+
+func (c *Cache) sync(ctx context.Context, uri string, path filepathx.DirPath) error {
+	if path.Exist() {
+		_, err := execx.NewCommand(c.command, "remote", "update", "--prune").
+			Execute(ctx, execx.WithDir(path), execx.WithEnv(c.env))
+		return err
+	}
+	_, err := execx.NewCommand(c.command, "clone", "--mirror", uri, path.Tail()).
+		Execute(ctx, execx.WithDir(c.dir), execx.WithEnv(c.env))
+	if err != nil && path.Exist() {
+		// path did not exist under the lock, this clone created it
+		_ = path.Remove()
+	}
+	return err
+}
+
+type Entry struct {
+	Path     filepathx.DirPath
+	LastUsed time.Time
+}
+
+// List returns the mirror repositories, least recently used first.
+func (c *Cache) List() ([]*Entry, error) {
+	if !c.dir.Exist() {
+		return nil, nil
+	}
+	entries, err := os.ReadDir(c.dir.String())
+	if err != nil {
+		return nil, errors.Join(ErrMirror, err)
+	}
+	var list []*Entry
+	for _, entry := range entries {
+		if !entry.IsDir() || !strings.HasSuffix(entry.Name(), ".git") {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return nil, errors.Join(ErrMirror, err)
+		}
+		list = append(list, &Entry{
+			Path:     c.dir.Join(entry.Name()).DirPath(),
+			LastUsed: info.ModTime(),
+		})
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].LastUsed.Before(list[j].LastUsed)
+	})
+	return list, nil
+}
+
+// Prune removes the mirror repositories not used since now - age.
+// Returns the removed entries, or the entries to be removed if dry.
+// Each mirror is locked while removing, and kept if it was used while waiting for the lock.
+func (c *Cache) Prune(ctx context.Context, now time.Time, age time.Duration, dry bool) ([]*Entry, error) {
+	list, err := c.List()
+	if err != nil {
+		return nil, err
+	}
+	threshold := now.Add(-age)
+	var pruned []*Entry
+	for _, entry := range list {
+		if entry.LastUsed.After(threshold) {
+			continue
+		}
+		if !dry {
+			removed, err := c.prune(ctx, entry.Path, threshold)
+			if err != nil {
+				return pruned, errorx.Errorf(errors.Join(ErrMirror, err), "prune %s", entry.Path)
+			}
+			if !removed {
+				continue
+			}
+		}
+		pruned = append(pruned, entry)
+	}
+	return pruned, nil
+}
+
+// prune removes path under the lock if it is not used since threshold.
+func (c *Cache) prune(ctx context.Context, path filepathx.DirPath, threshold time.Time) (bool, error) {
+	lock, err := proclock.Acquire(ctx, c.lockPath(path), true, 0)
+	if err != nil {
+		return false, err
+	}
+	defer func() {
+		_ = lock.Release()
+	}()
+	info, err := os.Stat(path.String())
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return false, nil
+		}
+		return false, err
+	}
+	if info.ModTime().After(threshold) {
+		return false, nil
+	}
+	return true, path.Remove()
+}
@@ -0,0 +1,75 @@
+package mirror_test
+
+import (
+	"berquerant/install-via-git-go/execx"
+	"berquerant/install-via-git-go/filepathx"
+	"berquerant/install-via-git-go/mirror"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCache(t *testing.T) {
+	base, err := filepathx.NewPath(t.TempDir())
+	assert.Nil(t, err)
+
+	env := execx.EnvFromSlice([]string{
+		"GIT_AUTHOR_NAME=ivg",
+		"GIT_AUTHOR_EMAIL=ivg@example.com",
+		"GIT_COMMITTER_NAME=ivg",
+		"GIT_COMMITTER_EMAIL=ivg@example.com",
+	})
+	origin := base.Join("origin").DirPath()
+	assert.Nil(t, origin.Ensure())
+	_, err = execx.NewExecutorFromStrings([]string{
+		"git init",
+		"git commit --allow-empty -m init",
+	}, "bash").Execute(context.TODO(), execx.WithDir(origin), execx.WithEnv(env))
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	cache := mirror.NewCache(base.Join("mirrors").DirPath(), env, "git")
+	uri := origin.String()
+
+	t.Run("Sync", func(t *testing.T) {
+		_, ok := cache.Lookup(uri)
+		assert.False(t, ok)
+
+		path, err := cache.Sync(context.TODO(), uri)
+		assert.Nil(t, err)
+		assert.Equal(t, cache.Path(uri), path)
+		assert.True(t, path.Exist())
+
+		got, ok := cache.Lookup(uri)
+		assert.True(t, ok)
+		assert.Equal(t, path, got)
+
+		// update existing mirror
+		_, err = cache.Sync(context.TODO(), uri)
+		assert.Nil(t, err)
+
+		list, err := cache.List()
+		assert.Nil(t, err)
+		assert.Len(t, list, 1)
+	})
+
+	t.Run("Prune", func(t *testing.T) {
+		now := time.Now()
+		pruned, err := cache.Prune(context.TODO(), now, time.Hour, false)
+		assert.Nil(t, err)
+		assert.Len(t, pruned, 0, "recently used")
+
+		pruned, err = cache.Prune(context.TODO(), now.Add(time.Minute), 0, true)
+		assert.Nil(t, err)
+		assert.Len(t, pruned, 1)
+		assert.True(t, cache.Path(uri).Exist(), "dry")
+
+		pruned, err = cache.Prune(context.TODO(), now.Add(time.Minute), 0, false)
+		assert.Nil(t, err)
+		assert.Len(t, pruned, 1)
+		assert.False(t, cache.Path(uri).Exist())
+	})
+}
@@ -0,0 +1,125 @@
+package proclock
+
+import (
+	"berquerant/install-via-git-go/errorx"
+	"berquerant/install-via-git-go/filepathx"
+	"berquerant/install-via-git-go/logx"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrProcLock = errors.New("ProcLock")
+	// ErrLocked means that the lock is held by another process.
+	ErrLocked = errors.New("Locked")
+)
+
+// FileName is the process lock file in workDir.
+const FileName = ".ivg.lock"
+
+// PollInterval is the interval to retry acquiring the lock while waiting.
+var PollInterval = 100 * time.Millisecond
+
+// Lock is an advisory lock on a file to prevent simultaneous runs.
+type Lock struct {
+	path filepathx.FilePath
+	file *os.File
+}
+
+// FromWorkDir returns the process lock path of workDir.
+func FromWorkDir(workDir filepathx.DirPath) filepathx.FilePath {
+	return workDir.Join(FileName).FilePath()
+}
+
+// Acquire locks path and writes the current pid into it.
+// If wait, retries until the lock is released, timeout or ctx is done; timeout 0 means no limit.
+// Returns ErrLocked naming the pid of the holder if the lock is held by another process.
+func Acquire(ctx context.Context, path filepathx.FilePath, wait bool, timeout time.Duration) (*Lock, error) {
+	f, err := os.OpenFile(path.String(), os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, errorx.Errorf(errors.Join(ErrProcLock, err), "open %s", path)
+	}
+	if wait && timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	for waiting := false; ; waiting = true {
+		err := tryLock(f)
+		if err == nil {
+			break
+		}
+		if !errors.Is(err, errWouldBlock) {
+			_ = f.Close()
+			return nil, errorx.Errorf(errors.Join(ErrProcLock, err), "lock %s", path)
+		}
+		holder := readHolder(f)
+		if !wait {
+			_ = f.Close()
+			return nil, errorx.Errorf(errors.Join(ErrProcLock, ErrLocked), "%s held by pid %s", path, holder)
+		}
+		if !waiting {
+			logx.Info("wait for lock", logx.S("path", path.String()), logx.S("pid", holder))
+		}
+		select {
+		case <-ctx.Done():
+			_ = f.Close()
+			return nil, errorx.Errorf(errors.Join(ErrProcLock, ErrLocked, ctx.Err()), "%s held by pid %s", path, holder)
+		case <-time.After(PollInterval):
+		}
+	}
+
+	if err := writeHolder(f); err != nil {
+		_ = unlock(f)
+		_ = f.Close()
+		return nil, errorx.Errorf(errors.Join(ErrProcLock, err), "write pid into %s", path)
+	}
+	logx.Debug("acquire lock", logx.S("path", path.String()))
+	return &Lock{
+		path: path,
+		file: f,
+	}, nil
+}
+
+func (l *Lock) Path() filepathx.FilePath {
+	return l.path
+}
+
+// Release unlocks the file, the file remains to avoid racing with the other processes opening it.
+func (l *Lock) Release() error {
+	logx.Debug("release lock", logx.S("path", l.path.String()))
+	_ = l.file.Truncate(0)
+	err := unlock(l.file)
+	if cerr := l.file.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return errorx.Errorf(errors.Join(ErrProcLock, err), "release %s", l.path)
+	}
+	return nil
+}
+
+func writeHolder(f *os.File) error {
+	if err := f.Truncate(0); err != nil {
+		return err
+	}
+	_, err := f.WriteAt([]byte(fmt.Sprintf("%d\n", os.Getpid())), 0)
+	return err
+}
+
+// readHolder returns the pid written by the holder, unknown if not available.
+func readHolder(f *os.File) string {
+	b := make([]byte, 32)
+	n, _ := f.ReadAt(b, 0)
+	v := strings.TrimSpace(string(b[:n]))
+	if _, err := strconv.Atoi(v); err != nil {
+		return "unknown"
+	}
+	return v
+}
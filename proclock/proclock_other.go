@@ -0,0 +1,15 @@
+//go:build !unix
+
+package proclock
+
+import (
+	"errors"
+	"os"
+)
+
+var errWouldBlock = errors.New("would block")
+
+// tryLock always succeeds because flock is not available on this platform.
+func tryLock(*os.File) error { return nil }
+
+func unlock(*os.File) error { return nil }
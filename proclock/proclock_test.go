@@ -0,0 +1,59 @@
+//go:build unix
+
+package proclock_test
+
+import (
+	"berquerant/install-via-git-go/filepathx"
+	"berquerant/install-via-git-go/proclock"
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAcquire(t *testing.T) {
+	dir, err := filepathx.NewPath(t.TempDir())
+	if !assert.Nil(t, err) {
+		return
+	}
+	path := proclock.FromWorkDir(dir.DirPath())
+	proclock.PollInterval = 10 * time.Millisecond
+	ctx := context.TODO()
+
+	l, err := proclock.Acquire(ctx, path, false, 0)
+	if !assert.Nil(t, err) {
+		return
+	}
+	content, err := os.ReadFile(path.String())
+	assert.Nil(t, err)
+	assert.Equal(t, fmt.Sprintf("%d\n", os.Getpid()), string(content))
+
+	t.Run("locked", func(t *testing.T) {
+		_, err := proclock.Acquire(ctx, path, false, 0)
+		assert.ErrorIs(t, err, proclock.ErrLocked)
+		assert.ErrorContains(t, err, fmt.Sprintf("pid %d", os.Getpid()))
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		_, err := proclock.Acquire(ctx, path, true, 50*time.Millisecond)
+		assert.ErrorIs(t, err, proclock.ErrLocked)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+
+	t.Run("wait", func(t *testing.T) {
+		released := make(chan error)
+		go func() {
+			time.Sleep(50 * time.Millisecond)
+			released <- l.Release()
+		}()
+		w, err := proclock.Acquire(ctx, path, true, time.Second)
+		if !assert.Nil(t, err) {
+			return
+		}
+		assert.Nil(t, <-released)
+		assert.Nil(t, w.Release())
+	})
+}
@@ -0,0 +1,202 @@
+// Package remoteconfig reads the config from a git repository.
+package remoteconfig
+
+import (
+	"berquerant/install-via-git-go/errorx"
+	"berquerant/install-via-git-go/execx"
+	"berquerant/install-via-git-go/filepathx"
+	"berquerant/install-via-git-go/git"
+	"berquerant/install-via-git-go/logx"
+	"berquerant/install-via-git-go/proclock"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+var (
+	ErrRemoteConfig = errors.New("RemoteConfig")
+)
+
+const scheme = "git+"
+
+// Location is the config file in a git repository,
+// written as git+REPO//PATH@REF, e.g. git+https://example.com/toolchain.git//tools/ivg.yml@main.
+type Location struct {
+	// Repo is the uri of the repository.
+	Repo string
+	// Path is the path of the config file in the repository.
+	Path string
+	// Ref is the branch, tag or commit, default is the default branch of the repository.
+	Ref string
+}
+
+func (l Location) String() string {
+	s := scheme + l.Repo + "//" + l.Path
+	if l.Ref != "" {
+		s += "@" + l.Ref
+	}
+	return s
+}
+
+// IsLocation returns true if s is written as Location.
+func IsLocation(s string) bool {
+	return strings.HasPrefix(s, scheme)
+}
+
+// ParseLocation parses git+REPO//PATH@REF.
+func ParseLocation(s string) (*Location, error) {
+	if !IsLocation(s) {
+		return nil, errorx.Errorf(ErrRemoteConfig, "not %sREPO//PATH: %s", scheme, s)
+	}
+	v := strings.TrimPrefix(s, scheme)
+	// skip :// of the repo uri
+	start := 0
+	if i := strings.Index(v, "://"); i >= 0 {
+		start = i + len("://")
+	}
+	i := strings.Index(v[start:], "//")
+	if i < 0 {
+		return nil, errorx.Errorf(ErrRemoteConfig, "no //PATH: %s", s)
+	}
+	repo, p := v[:start+i], v[start+i+len("//"):]
+	var ref string
+	if j := strings.LastIndex(p, "@"); j >= 0 {
+		p, ref = p[:j], p[j+1:]
+	}
+	if repo == "" || p == "" {
+		return nil, errorx.Errorf(ErrRemoteConfig, "empty repo or path: %s", s)
+	}
+	if !filepath.IsLocal(p) {
+		return nil, errorx.Errorf(ErrRemoteConfig, "path is not in the repository: %s", s)
+	}
+	return &Location{
+		Repo: repo,
+		Path: path.Clean(p),
+		Ref:  ref,
+	}, nil
+}
+
+// DefaultDir returns the default remote config cache directory, ~/.cache/install-via-git/configs.
+func DefaultDir() (filepathx.DirPath, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return filepathx.DirPath{}, errors.Join(ErrRemoteConfig, err)
+	}
+	p, err := filepathx.NewPath(filepath.Join(dir, "install-via-git", "configs"))
+	if err != nil {
+		return filepathx.DirPath{}, errors.Join(ErrRemoteConfig, err)
+	}
+	return p.DirPath(), nil
+}
+
+// Cache is a set of the clones of the config repositories.
+type Cache struct {
+	dir     filepathx.DirPath
+	env     execx.Env
+	command string
+	opt     []git.ConfigOption
+}
+
+func NewCache(dir filepathx.DirPath, env execx.Env, command string, opt ...git.ConfigOption) *Cache {
+	return &Cache{
+		dir:     dir,
+		env:     env,
+		command: command,
+		opt:     opt,
+	}
+}
+
+// Path returns the clone of repo.
+func (c *Cache) Path(repo string) filepathx.DirPath {
+	return c.dir.Join(c.name(repo)).DirPath()
+}
+
+// lockPath returns the lock of the clone of repo.
+func (c *Cache) lockPath(repo string) filepathx.FilePath {
+	return c.dir.Join(c.name(repo) + ".lock").FilePath()
+}
+
+func (c *Cache) name(repo string) string {
+	sum := sha256.Sum256([]byte(repo))
+	return hex.EncodeToString(sum[:])
+}
+
+// Checkout clones or fetches the repository of loc, checkouts the ref
+// and returns the config file in the clone.
+//
+// The clone is locked against the other processes until the returned lock is released,
+// release it after reading the config and the files it extends.
+func (c *Cache) Checkout(ctx context.Context, loc *Location) (filepathx.FilePath, *proclock.Lock, error) {
+	if err := c.dir.Ensure(); err != nil {
+		return filepathx.FilePath{}, nil, errors.Join(ErrRemoteConfig, err)
+	}
+	lock, err := proclock.Acquire(ctx, c.lockPath(loc.Repo), true, 0)
+	if err != nil {
+		return filepathx.FilePath{}, nil, errors.Join(ErrRemoteConfig, err)
+	}
+	file, err := c.checkout(ctx, loc)
+	if err != nil {
+		_ = lock.Release()
+		return filepathx.FilePath{}, nil, err
+	}
+	return file, lock, nil
+}
+
+func (c *Cache) checkout(ctx context.Context, loc *Location) (filepathx.FilePath, error) {
+	dir := c.Path(loc.Repo)
+	cli := git.NewCLI(dir, c.env, c.command, c.opt...)
+	command := git.NewCommand(cli, c.opt...)
+
+	if dir.Exist() {
+		logx.Info("fetch config repo", logx.S("repo", loc.Repo), logx.S("path", dir.String()))
+		if err := fetch(ctx, cli); err != nil {
+			return filepathx.FilePath{}, errors.Join(ErrRemoteConfig, errorx.Errorf(err, "fetch %s", loc.Repo))
+		}
+	} else {
+		logx.Info("clone config repo", logx.S("repo", loc.Repo), logx.S("path", dir.String()))
+		if err := command.Clone(ctx, loc.Repo); err != nil {
+			return filepathx.FilePath{}, errors.Join(ErrRemoteConfig, errorx.Errorf(err, "clone %s", loc.Repo))
+		}
+	}
+
+	commit, err := resolve(ctx, cli, loc.Ref)
+	if err != nil {
+		return filepathx.FilePath{}, errors.Join(ErrRemoteConfig, errorx.Errorf(err, "resolve %s", loc))
+	}
+	logx.Info("config commit", logx.S("location", loc.String()), logx.S("commit", commit))
+	if err := command.Checkout(ctx, commit); err != nil {
+		return filepathx.FilePath{}, errors.Join(ErrRemoteConfig, errorx.Errorf(err, "checkout %s", commit))
+	}
+
+	file := dir.Join(loc.Path).FilePath()
+	if !file.Exist() {
+		return filepathx.FilePath{}, errorx.Errorf(ErrRemoteConfig, "%s not found", loc)
+	}
+	return file, nil
+}
+
+// fetch updates the branches, the tags and origin/HEAD, ref may be a moved tag or the default branch.
+func fetch(ctx context.Context, cli git.CLI) error {
+	if _, err := cli.Execute(ctx, "fetch", "--prune", "--prune-tags", "--tags", "--force", "origin"); err != nil {
+		return err
+	}
+	_, err := cli.Execute(ctx, "remote", "set-head", "origin", "--auto")
+	return err
+}
+
+// resolve returns the commit of ref.
+// A branch is resolved to the remote-tracking branch, as the local branch is not updated by fetch.
+func resolve(ctx context.Context, cli git.CLI, ref string) (string, error) {
+	if ref == "" {
+		return cli.Execute(ctx, "rev-parse", "--verify", "origin/HEAD^{commit}")
+	}
+	if commit, err := cli.Execute(ctx, "rev-parse", "--verify", "--quiet", "origin/"+ref+"^{commit}"); err == nil {
+		return commit, nil
+	}
+	return cli.Execute(ctx, "rev-parse", "--verify", ref+"^{commit}")
+}
@@ -0,0 +1,180 @@
+package remoteconfig_test
+
+import (
+	"berquerant/install-via-git-go/execx"
+	"berquerant/install-via-git-go/filepathx"
+	"berquerant/install-via-git-go/proclock"
+	"berquerant/install-via-git-go/remoteconfig"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseLocation(t *testing.T) {
+	for _, tc := range []struct {
+		title   string
+		input   string
+		want    *remoteconfig.Location
+		wantErr bool
+	}{
+		{
+			title: "https",
+			input: "git+https://example.com/toolchain.git//tools/ivg.yml@v1.0",
+			want: &remoteconfig.Location{
+				Repo: "https://example.com/toolchain.git",
+				Path: "tools/ivg.yml",
+				Ref:  "v1.0",
+			},
+		},
+		{
+			title: "no ref",
+			input: "git+https://example.com/toolchain.git//ivg.yml",
+			want: &remoteconfig.Location{
+				Repo: "https://example.com/toolchain.git",
+				Path: "ivg.yml",
+			},
+		},
+		{
+			title: "ssh",
+			input: "git+ssh://git@example.com/toolchain.git//ivg.yml@main",
+			want: &remoteconfig.Location{
+				Repo: "ssh://git@example.com/toolchain.git",
+				Path: "ivg.yml",
+				Ref:  "main",
+			},
+		},
+		{
+			title: "file",
+			input: "git+file:///src/toolchain//a/ivg.yml@feature/x",
+			want: &remoteconfig.Location{
+				Repo: "file:///src/toolchain",
+				Path: "a/ivg.yml",
+				Ref:  "feature/x",
+			},
+		},
+		{
+			title: "local path",
+			input: "git+/src/toolchain//ivg.yml",
+			want: &remoteconfig.Location{
+				Repo: "/src/toolchain",
+				Path: "ivg.yml",
+			},
+		},
+		{title: "no scheme", input: "https://example.com/toolchain.git//ivg.yml", wantErr: true},
+		{title: "no path", input: "git+https://example.com/toolchain.git", wantErr: true},
+		{title: "empty path", input: "git+https://example.com/toolchain.git//@main", wantErr: true},
+		{title: "outside", input: "git+https://example.com/toolchain.git//../ivg.yml", wantErr: true},
+	} {
+		t.Run(tc.title, func(t *testing.T) {
+			got, err := remoteconfig.ParseLocation(tc.input)
+			if tc.wantErr {
+				assert.ErrorIs(t, err, remoteconfig.ErrRemoteConfig)
+				return
+			}
+			if !assert.Nil(t, err) {
+				return
+			}
+			assert.Equal(t, tc.want, got)
+			assert.Equal(t, tc.input, got.String())
+		})
+	}
+}
+
+func TestCache(t *testing.T) {
+	base, err := filepathx.NewPath(t.TempDir())
+	assert.Nil(t, err)
+
+	env := execx.EnvFromSlice([]string{
+		"GIT_AUTHOR_NAME=ivg",
+		"GIT_AUTHOR_EMAIL=ivg@example.com",
+		"GIT_COMMITTER_NAME=ivg",
+		"GIT_COMMITTER_EMAIL=ivg@example.com",
+	})
+	origin := base.Join("origin").DirPath()
+	assert.Nil(t, origin.Ensure())
+	git := func(t *testing.T, script ...string) {
+		t.Helper()
+		_, err := execx.NewExecutorFromStrings(script, "bash").
+			Execute(context.TODO(), execx.WithDir(origin), execx.WithEnv(env))
+		if !assert.Nil(t, err) {
+			t.FailNow()
+		}
+	}
+	git(t,
+		"git init -b main",
+		"mkdir tools && echo v1 > tools/ivg.yml",
+		"git add . && git commit -m v1 && git tag v1",
+	)
+
+	cache := remoteconfig.NewCache(base.Join("configs").DirPath(), env, "git")
+	checkout := func(t *testing.T, ref string) string {
+		t.Helper()
+		file, lock, err := cache.Checkout(context.TODO(), &remoteconfig.Location{
+			Repo: origin.String(),
+			Path: "tools/ivg.yml",
+			Ref:  ref,
+		})
+		if !assert.Nil(t, err) {
+			t.FailNow()
+		}
+		defer func() {
+			assert.Nil(t, lock.Release())
+		}()
+		content, err := file.Read()
+		assert.Nil(t, err)
+		return content
+	}
+
+	assert.Contains(t, checkout(t, ""), "v1")
+	git(t, "echo v2 > tools/ivg.yml && git commit -am v2")
+	assert.Contains(t, checkout(t, "main"), "v2")
+	assert.Contains(t, checkout(t, "v1"), "v1")
+	assert.Contains(t, checkout(t, ""), "v2")
+
+	t.Run("new tag", func(t *testing.T) {
+		git(t, "git tag v2")
+		assert.Contains(t, checkout(t, "v2"), "v2")
+	})
+
+	t.Run("moved tag", func(t *testing.T) {
+		git(t, "echo v3 > tools/ivg.yml && git commit -am v3 && git tag -f v2")
+		assert.Contains(t, checkout(t, "v2"), "v3")
+	})
+
+	t.Run("default branch changed", func(t *testing.T) {
+		git(t,
+			"git checkout -b next",
+			"echo next > tools/ivg.yml && git commit -am next",
+			"git symbolic-ref HEAD refs/heads/next",
+		)
+		assert.Contains(t, checkout(t, ""), "next")
+	})
+
+	t.Run("missing", func(t *testing.T) {
+		_, _, err := cache.Checkout(context.TODO(), &remoteconfig.Location{
+			Repo: origin.String(),
+			Path: "missing.yml",
+		})
+		assert.ErrorIs(t, err, remoteconfig.ErrRemoteConfig)
+	})
+
+	t.Run("locked", func(t *testing.T) {
+		loc := &remoteconfig.Location{
+			Repo: origin.String(),
+			Path: "tools/ivg.yml",
+		}
+		_, lock, err := cache.Checkout(context.TODO(), loc)
+		if !assert.Nil(t, err) {
+			return
+		}
+		defer func() {
+			assert.Nil(t, lock.Release())
+		}()
+		ctx, cancel := context.WithTimeout(context.TODO(), 300*time.Millisecond)
+		defer cancel()
+		_, _, err = cache.Checkout(ctx, loc)
+		assert.ErrorIs(t, err, proclock.ErrLocked)
+	})
+}
@@ -10,6 +10,10 @@ import (
 type Backuper interface {
 	Create() error
 	Restore() error
+	// Close removes the created backup.
+	Close() error
+	// Backup returns the created backup, nil if nothing created.
+	Backup() backup.Maker
 }
 
 type BackupList []Backuper
@@ -30,24 +34,45 @@ func (b BackupList) Restore() error {
 	})
 }
 
+func (b BackupList) Close() error {
+	return errorx.Serial(b, func(x Backuper) error {
+		return x.Close()
+	})
+}
+
+// Backups returns the created backups.
+func (b BackupList) Backups() []backup.Maker {
+	var backups []backup.Maker
+	for _, x := range b {
+		if v := x.Backup(); v != nil {
+			backups = append(backups, v)
+		}
+	}
+	return backups
+}
+
 type NoopBackup struct{}
 
-func (*NoopBackup) Create() error  { return nil }
-func (*NoopBackup) Restore() error { return nil }
+func (*NoopBackup) Create() error        { return nil }
+func (*NoopBackup) Restore() error       { return nil }
+func (*NoopBackup) Close() error         { return nil }
+func (*NoopBackup) Backup() backup.Maker { return nil }
 
 type LockFileBackup struct {
-	backupFile *backup.Backup
+	backupFile backup.Maker
 	origin     filepathx.FilePath
 	commit     string
+	storage    backup.Storage
 }
 
-func NewLockFileBackup(origin filepathx.FilePath, commit string, clean bool) Backuper {
+func NewLockFileBackup(origin filepathx.FilePath, commit string, clean bool, storage backup.Storage) Backuper {
 	if !(clean || commit != "") {
 		return &NoopBackup{}
 	}
 	return &LockFileBackup{
-		origin: origin,
-		commit: commit,
+		origin:  origin,
+		commit:  commit,
+		storage: storage,
 	}
 }
 
@@ -57,7 +82,7 @@ func (b *LockFileBackup) Create() error {
 		logx.S("explicitCommit", b.commit),
 	)
 	// override current commit by explicit commit
-	backupFile, err := backup.IntoTempDir(b.origin.Path)
+	backupFile, err := b.storage.Create(b.origin.Path)
 	if err != nil {
 		return errorx.Errorf(err, "create backup")
 	}
@@ -65,35 +90,48 @@ func (b *LockFileBackup) Create() error {
 		return errorx.Errorf(err, "move backup")
 	}
 
-	if err := b.origin.Write(b.commit); err != nil {
+	if err := b.origin.WriteAtomic(b.commit); err != nil {
 		return errorx.Errorf(err, "override commit")
 	}
 	b.backupFile = backupFile
 	return nil
 }
 
+func (b *LockFileBackup) Backup() backup.Maker {
+	return b.backupFile
+}
+
+func (b *LockFileBackup) Close() error {
+	if b.backupFile == nil {
+		return nil
+	}
+	return b.backupFile.Close()
+}
+
 func (b *LockFileBackup) Restore() error {
 	defer b.backupFile.Close()
 	return b.backupFile.Restore()
 }
 
 type RepoBackup struct {
-	backupDir  *backup.Backup
+	backupDir  backup.Maker
 	gitWorkDir filepathx.DirPath
+	storage    backup.Storage
 }
 
-func NewRepoBackup(gitWorkDir filepathx.DirPath, clean bool) Backuper {
+func NewRepoBackup(gitWorkDir filepathx.DirPath, clean bool, storage backup.Storage) Backuper {
 	if !(clean || gitWorkDir.Exist()) {
 		return &NoopBackup{}
 	}
 	return &RepoBackup{
 		gitWorkDir: gitWorkDir,
+		storage:    storage,
 	}
 }
 
 func (b *RepoBackup) Create() error {
-	logx.Info("backup repo", logx.S("path", b.gitWorkDir.String()))
-	repoBackup, err := backup.IntoTempDir(b.gitWorkDir.Path)
+	logx.Info("backup repo", logx.S("path", b.gitWorkDir.String()), logx.S("storage", b.storage.String()))
+	repoBackup, err := b.storage.Create(b.gitWorkDir.Path)
 	if err != nil {
 		return errorx.Errorf(err, "create backup")
 	}
@@ -104,6 +142,17 @@ func (b *RepoBackup) Create() error {
 	return nil
 }
 
+func (b *RepoBackup) Backup() backup.Maker {
+	return b.backupDir
+}
+
+func (b *RepoBackup) Close() error {
+	if b.backupDir == nil {
+		return nil
+	}
+	return b.backupDir.Close()
+}
+
 func (b *RepoBackup) Restore() error {
 	defer b.backupDir.Close()
 	return b.backupDir.Restore()
@@ -28,7 +28,7 @@ func NewRollback(
 func (r *Rollback) Run(ctx context.Context) error {
 	if r.noupdate {
 		logx.Info("skip rollback repo and lockfile")
-		if _, err := execx.NewExecutorFromStrings(r.Config.Steps.Rollback, r.Shell...).
+		if _, err := r.Executor(r.Config.Steps.Rollback).
 			Execute(ctx, execx.WithDir(r.LocalRepoDir), execx.WithEnv(r.Env)); err != nil {
 			logx.Error("run rollback", logx.Err(err))
 		}
@@ -39,7 +39,7 @@ func (r *Rollback) Run(ctx context.Context) error {
 	if err := r.keeper.Rollback(ctx); err != nil {
 		logx.Error("rollback error", logx.Err(err))
 	}
-	if _, err := execx.NewExecutorFromStrings(r.Config.Steps.Rollback, r.Shell...).
+	if _, err := r.Executor(r.Config.Steps.Rollback).
 		Execute(ctx, execx.WithDir(r.LocalRepoDir), execx.WithEnv(r.Env)); err != nil {
 		logx.Error("run rollback", logx.Err(err))
 	}
@@ -4,6 +4,7 @@ import (
 	"berquerant/install-via-git-go/config"
 	"berquerant/install-via-git-go/execx"
 	"berquerant/install-via-git-go/filepathx"
+	"berquerant/install-via-git-go/logx"
 	"context"
 )
 
@@ -17,3 +18,14 @@ type Argument struct {
 	Shell        []string
 	LocalRepoDir filepathx.DirPath
 }
+
+// Executor returns the executor of the steps whose when matches the current platform.
+func (a *Argument) Executor(steps []config.Step) execx.Executor {
+	platform := config.NewPlatform(a.Env)
+	for _, s := range steps {
+		if !s.When.Match(platform) {
+			logx.Info("skip step", logx.S("run", s.Run))
+		}
+	}
+	return execx.NewExecutorFromStrings(config.Scripts(steps, platform), a.Shell...)
+}
@@ -9,18 +9,25 @@ import (
 	"errors"
 )
 
+// Hook runs between the strategy and install.
+type Hook func(ctx context.Context) error
+
 type Strategy struct {
 	*Argument
-	runner strategy.Runner
+	runner        strategy.Runner
+	beforeInstall []Hook
 }
 
+// NewStrategy returns a Strategy, beforeInstall run in order.
 func NewStrategy(
 	argument *Argument,
 	runner strategy.Runner,
+	beforeInstall ...Hook,
 ) *Strategy {
 	return &Strategy{
-		Argument: argument,
-		runner:   runner,
+		Argument:      argument,
+		runner:        runner,
+		beforeInstall: beforeInstall,
 	}
 }
 
@@ -31,15 +38,21 @@ func (s *Strategy) Run(ctx context.Context) error {
 		}
 
 		logx.Info("skip")
-		if _, err := execx.NewExecutorFromStrings(s.Config.Steps.Skip, s.Shell...).
+		if _, err := s.Executor(s.Config.Steps.Skip).
 			Execute(ctx, execx.WithDir(s.LocalRepoDir), execx.WithEnv(s.Env)); err != nil {
 			return errorx.Errorf(err, "run skip")
 		}
 		return nil
 	}
 
+	for _, hook := range s.beforeInstall {
+		if err := hook(ctx); err != nil {
+			return errorx.Errorf(err, "before install")
+		}
+	}
+
 	logx.Info("install")
-	if _, err := execx.NewExecutorFromStrings(s.Config.Steps.Install, s.Shell...).
+	if _, err := s.Executor(s.Config.Steps.Install).
 		Execute(ctx, execx.WithDir(s.LocalRepoDir), execx.WithEnv(s.Env)); err != nil {
 		return errorx.Errorf(err, "run install")
 	}
@@ -27,7 +27,7 @@ func NewUninstall(
 func (u *Uninstall) Run(ctx context.Context) error {
 	if u.LocalRepoDir.Exist() {
 		logx.Info("uninstall")
-		if _, err := execx.NewExecutorFromStrings(u.Config.Steps.Uninstall, u.Shell...).
+		if _, err := u.Executor(u.Config.Steps.Uninstall).
 			Execute(ctx, execx.WithDir(u.LocalRepoDir), execx.WithEnv(u.Env)); err != nil {
 			return errorx.Errorf(err, "run uninstall")
 		}
@@ -0,0 +1,76 @@
+// Package scaffold generates a config from a repository.
+package scaffold
+
+import (
+	"berquerant/install-via-git-go/config"
+	"berquerant/install-via-git-go/filepathx"
+)
+
+// BuildSystem is a build system detected by the marker file.
+type BuildSystem struct {
+	Name string
+	// File is the marker file at the root of the repository.
+	File string
+	// Install is the install steps.
+	Install []string
+}
+
+// BuildSystems are the known build systems in the order of priority.
+// Makefile comes first because it often wraps the others.
+var BuildSystems = []*BuildSystem{
+	{
+		Name:    "make",
+		File:    "Makefile",
+		Install: []string{"make", "make install"},
+	},
+	{
+		Name:    "go",
+		File:    "go.mod",
+		Install: []string{"go install ./..."},
+	},
+	{
+		Name:    "cargo",
+		File:    "Cargo.toml",
+		Install: []string{"cargo install --path ."},
+	},
+	{
+		Name: "cmake",
+		File: "CMakeLists.txt",
+		Install: []string{
+			"cmake -S . -B build -DCMAKE_BUILD_TYPE=Release",
+			"cmake --build build",
+			"cmake --install build",
+		},
+	},
+	{
+		Name:    "npm",
+		File:    "package.json",
+		Install: []string{"npm ci", "npm run build --if-present", "npm install --global ."},
+	},
+}
+
+// Detect returns the build systems of the repository in the order of priority.
+func Detect(dir filepathx.DirPath) []*BuildSystem {
+	var r []*BuildSystem
+	for _, b := range BuildSystems {
+		if dir.Join(b.File).FilePath().Exist() {
+			r = append(r, b)
+		}
+	}
+	return r
+}
+
+// Generate returns the config to install uri.
+// The install steps are of the first build system, empty if no build system.
+func Generate(uri, branch string, systems []*BuildSystem) *config.Config {
+	cfg := &config.Config{
+		URI:    config.URIs{uri},
+		Branch: branch,
+	}
+	if len(systems) > 0 {
+		for _, x := range systems[0].Install {
+			cfg.Steps.Install = append(cfg.Steps.Install, config.Step{Run: x})
+		}
+	}
+	return cfg
+}
@@ -0,0 +1,61 @@
+package scaffold_test
+
+import (
+	"berquerant/install-via-git-go/config"
+	"berquerant/install-via-git-go/filepathx"
+	"berquerant/install-via-git-go/scaffold"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDetect(t *testing.T) {
+	for _, tc := range []struct {
+		title string
+		files []string
+		want  []string
+	}{
+		{title: "none"},
+		{title: "go", files: []string{"go.mod", "main.go"}, want: []string{"go"}},
+		{title: "cargo", files: []string{"Cargo.toml"}, want: []string{"cargo"}},
+		{title: "cmake", files: []string{"CMakeLists.txt"}, want: []string{"cmake"}},
+		{title: "npm", files: []string{"package.json"}, want: []string{"npm"}},
+		{
+			title: "make first",
+			files: []string{"go.mod", "Makefile"},
+			want:  []string{"make", "go"},
+		},
+	} {
+		t.Run(tc.title, func(t *testing.T) {
+			dir := t.TempDir()
+			for _, f := range tc.files {
+				assert.Nil(t, os.WriteFile(filepath.Join(dir, f), nil, 0644))
+			}
+			p, err := filepathx.NewPath(dir)
+			if !assert.Nil(t, err) {
+				return
+			}
+			var got []string
+			for _, b := range scaffold.Detect(p.DirPath()) {
+				got = append(got, b.Name)
+			}
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	t.Run("none", func(t *testing.T) {
+		got := scaffold.Generate("https://example.com/repo.git", "develop", nil)
+		assert.Equal(t, &config.Config{
+			URI:    config.URIs{"https://example.com/repo.git"},
+			Branch: "develop",
+		}, got)
+	})
+	t.Run("first", func(t *testing.T) {
+		got := scaffold.Generate("https://example.com/repo.git", "main", scaffold.BuildSystems[1:3])
+		assert.Equal(t, []config.Step{{Run: "go install ./..."}}, got.Steps.Install)
+	})
+}
@@ -1,6 +1,10 @@
 package strategy
 
 import (
+	"berquerant/install-via-git-go/errorx"
+	"berquerant/install-via-git-go/git"
+	"berquerant/install-via-git-go/lock"
+	"berquerant/install-via-git-go/logx"
 	"context"
 	"errors"
 )
@@ -15,6 +19,8 @@ var (
 	ErrNoopStrategy    = errors.New("NoopStrategy")
 	ErrUnknownStrategy = errors.New("UnknownStrategy")
 	ErrNoLock          = errors.New("NoLock")
+	ErrNetworkRequired = errors.New("NetworkRequired")
+	ErrHashMismatch    = errors.New("HashMismatch")
 )
 
 func NewUpdateToLatestWithLock(c RunnerConfig) *UpdateToLatestWithLock {
@@ -32,7 +38,7 @@ func (r *UpdateToLatestWithLock) Run(ctx context.Context) error {
 	if current == "" {
 		return ErrNoLock
 	}
-	_ = r.c.Command().Checkout(ctx, r.c.Branch())
+	checkoutBranch(ctx, r.c.Command(), r.c.Branch())
 	if err := r.c.Command().Fetch(ctx); err != nil {
 		return err
 	}
@@ -66,7 +72,7 @@ func (r *UpdateToLockRunner) Run(ctx context.Context) error {
 	if current == "" {
 		return ErrNoLock
 	}
-	_ = r.c.Command().Checkout(ctx, r.c.Branch())
+	checkoutBranch(ctx, r.c.Command(), r.c.Branch())
 	if err := r.c.Command().Fetch(ctx); err != nil {
 		return err
 	}
@@ -78,11 +84,56 @@ func (r *UpdateToLockRunner) Run(ctx context.Context) error {
 	if err != nil {
 		return err
 	}
-	if current == repoCurrent {
+	if resolved, err := r.c.Command().ResolveCommit(ctx, current); err == nil && resolved == repoCurrent {
+		normalizeLock(r.c.Pair(), resolved)
 		return nil
 	}
 
-	return r.c.Command().Checkout(ctx, current)
+	head, err := checkoutLock(ctx, r.c.Command(), current)
+	if err != nil {
+		return err
+	}
+	normalizeLock(r.c.Pair(), head)
+	return nil
+}
+
+// checkoutBranch checkouts branch before fetch to leave the detached HEAD.
+// The error is logged, not returned, because the branch may exist only on the remote until fetch,
+// then the later checkout or pull reports it.
+func checkoutBranch(ctx context.Context, command git.Command, branch string) {
+	if err := command.Checkout(ctx, branch); err != nil {
+		logx.Error("checkout before fetch", logx.S("branch", branch), logx.Err(err))
+	}
+}
+
+// checkoutLock checkouts commit and verifies that HEAD is commit whichever remote provided it.
+// commit may be an abbreviated hash, a tag or a branch, returns the full hash of HEAD.
+func checkoutLock(ctx context.Context, command git.Command, commit string) (string, error) {
+	want, err := command.ResolveCommit(ctx, commit)
+	if err != nil {
+		return "", errorx.Errorf(err, "resolve %s", commit)
+	}
+	if err := command.Checkout(ctx, want); err != nil {
+		return "", err
+	}
+	head, err := command.GetCommitHash(ctx)
+	if err != nil {
+		return "", err
+	}
+	if head != want {
+		return "", errorx.Errorf(ErrHashMismatch, "want %s (%s) got %s from %s", want, commit, head, command.Provider())
+	}
+	return head, nil
+}
+
+// normalizeLock replaces the lock value of pair with the full hash, and commits it if they differ.
+func normalizeLock(pair *lock.Pair, hash string) {
+	if pair.Current == hash {
+		return
+	}
+	logx.Info("normalize lock", logx.S("lock", pair.Current), logx.S("hash", hash))
+	pair.Current = hash
+	pair.Next = hash
 }
 
 func NewCreateLatestLockRunner(c RunnerConfig) *CreateLatestLockRunner {
@@ -102,7 +153,7 @@ func (r *CreateLatestLockRunner) Run(ctx context.Context) error {
 	}
 	r.c.Pair().Current = current
 
-	_ = r.c.Command().Checkout(ctx, r.c.Branch())
+	checkoutBranch(ctx, r.c.Command(), r.c.Branch())
 	if err := r.c.Command().Fetch(ctx); err != nil {
 		return err
 	}
@@ -132,7 +183,7 @@ type CreateLockRunner struct {
 }
 
 func (r *CreateLockRunner) Run(ctx context.Context) error {
-	_ = r.c.Command().Checkout(ctx, r.c.Branch())
+	checkoutBranch(ctx, r.c.Command(), r.c.Branch())
 	if err := r.c.Command().Fetch(ctx); err != nil {
 		return err
 	}
@@ -200,7 +251,12 @@ func (r *InitFromEmptyToLockRunner) Run(ctx context.Context) error {
 		return err
 	}
 
-	return r.c.Command().Checkout(ctx, r.c.Pair().Current)
+	head, err := checkoutLock(ctx, r.c.Command(), r.c.Pair().Current)
+	if err != nil {
+		return err
+	}
+	normalizeLock(r.c.Pair(), head)
+	return nil
 }
 
 func NewInitFromEmptyRunner(c RunnerConfig) *InitFromEmptyRunner {
@@ -1,8 +1,14 @@
 package strategy_test
 
 import (
+	"berquerant/install-via-git-go/execx"
+	"berquerant/install-via-git-go/filepathx"
+	"berquerant/install-via-git-go/git"
+	"berquerant/install-via-git-go/lock"
 	"berquerant/install-via-git-go/strategy"
 	"context"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -17,3 +23,123 @@ func TestUnknownRunner(t *testing.T) {
 	err := strategy.NewUnknownRunner().Run(context.TODO())
 	assert.ErrorIs(t, err, strategy.ErrUnknownStrategy)
 }
+
+func TestLockRunnerNormalizeLock(t *testing.T) {
+	basePath, err := filepathx.NewPath(t.TempDir())
+	if !assert.Nil(t, err) {
+		return
+	}
+	base := basePath.String()
+	src := filepath.Join(base, "src")
+	env := execx.EnvFromSlice([]string{
+		"GIT_AUTHOR_NAME=ivg",
+		"GIT_AUTHOR_EMAIL=ivg@example.com",
+		"GIT_COMMITTER_NAME=ivg",
+		"GIT_COMMITTER_EMAIL=ivg@example.com",
+	})
+	r, err := execx.NewExecutorFromStrings([]string{
+		"git init -q -b main src",
+		"cd src",
+		"echo 1 > f && git add f && git commit -q -m 1",
+		"git tag v1",
+		"git rev-parse HEAD",
+		"echo 2 > f && git commit -q -am 2",
+		"git rev-parse HEAD",
+	}, "bash").Execute(context.TODO(), execx.WithDir(basePath.DirPath()), execx.WithEnv(env))
+	if !assert.Nil(t, err) {
+		return
+	}
+	hashes := strings.Fields(r.Stdout)
+	if !assert.Len(t, hashes, 2) {
+		return
+	}
+	first, head := hashes[0], hashes[1]
+
+	for _, tc := range []struct {
+		title  string
+		cloned bool
+		lock   string
+		want   string
+		runner func(strategy.RunnerConfig) strategy.Runner
+	}{
+		{
+			title: "init from empty to tag",
+			lock:  "v1",
+			want:  first,
+		},
+		{
+			title: "init from empty to abbreviated hash",
+			lock:  first[:7],
+			want:  first,
+		},
+		{
+			title: "init from empty to full hash",
+			lock:  first,
+			want:  first,
+		},
+		{
+			title:  "update to abbreviated hash",
+			cloned: true,
+			lock:   first[:7],
+			want:   first,
+		},
+		{
+			title:  "update to abbreviated latest hash",
+			cloned: true,
+			lock:   head[:7],
+			want:   head,
+		},
+		{
+			title:  "update to tag",
+			cloned: true,
+			lock:   "v1",
+			want:   first,
+		},
+	} {
+		t.Run(tc.title, func(t *testing.T) {
+			p, err := filepathx.NewPath(filepath.Join(t.TempDir(), "repo"))
+			if !assert.Nil(t, err) {
+				return
+			}
+			if tc.cloned {
+				if _, err := execx.NewCommand("git", "clone", "-q", src, p.String()).
+					Execute(context.TODO(), execx.WithEnv(env)); !assert.Nil(t, err) {
+					return
+				}
+			}
+			command := git.NewCommand(git.NewCLI(p.DirPath(), execx.NewEnv(), "git"))
+			lockFile := filepathx.Path(filepath.Join(base, tc.title+".lock")).FilePath()
+			if !assert.Nil(t, lockFile.Write(tc.lock)) {
+				return
+			}
+			keeper := lock.NewFileKeeper(lockFile)
+			pair := keeper.Pair()
+			c := strategy.NewRunnerConfig(src, "main", pair, command)
+			var r strategy.Runner = strategy.NewInitFromEmptyToLockRunner(c)
+			if tc.cloned {
+				r = strategy.NewUpdateToLockRunner(c)
+			}
+			if !assert.Nil(t, r.Run(context.TODO())) {
+				return
+			}
+			assert.Equal(t, tc.want, pair.Current)
+			if tc.lock == tc.want {
+				// already normalized, nothing to commit
+				assert.Equal(t, "", pair.Next)
+			} else {
+				assert.Equal(t, tc.want, pair.Next)
+			}
+			got, err := command.GetCommitHash(context.TODO())
+			assert.Nil(t, err)
+			assert.Equal(t, tc.want, got)
+
+			if !assert.Nil(t, keeper.Commit()) {
+				return
+			}
+			committed, err := lockFile.Read()
+			assert.Nil(t, err)
+			content := lock.ParseContent(committed)
+			assert.Equal(t, tc.want, content.Hash)
+		})
+	}
+}
@@ -158,6 +158,28 @@ func (f Fact) SelectStrategy() Type {
 	return Tunknown
 }
 
+// RequiresNetwork returns true if the strategy needs the latest commits of the remote.
+func (t Type) RequiresNetwork() bool {
+	switch t {
+	case TinitFromEmptyToLatest, TcreateLatestLock, TupdateToLatestWithLock:
+		return true
+	default:
+		return false
+	}
+}
+
+// UsesBranch returns true if the strategy clones, fetches or pulls the branch.
+func (t Type) UsesBranch() bool {
+	switch t {
+	case TinitFromEmpty, TinitFromEmptyToLock, TinitFromEmptyToLatest,
+		TcreateLock, TcreateLatestLock,
+		TupdateToLock, TupdateToLatestWithLock:
+		return true
+	default:
+		return false
+	}
+}
+
 func (t Type) Runner(c RunnerConfig) Runner {
 	switch t {
 	case TinitFromEmpty:
@@ -190,3 +190,52 @@ func TestFact(t *testing.T) {
 
 	})
 }
+
+func TestType(t *testing.T) {
+	t.Run("RequiresNetwork", func(t *testing.T) {
+		for _, tc := range []struct {
+			t    strategy.Type
+			want bool
+		}{
+			{t: strategy.TinitFromEmpty},
+			{t: strategy.TinitFromEmptyToLock},
+			{t: strategy.TinitFromEmptyToLatest, want: true},
+			{t: strategy.TcreateLock},
+			{t: strategy.TcreateLatestLock, want: true},
+			{t: strategy.TupdateToLock},
+			{t: strategy.TupdateToLatestWithLock, want: true},
+			{t: strategy.Tnoop},
+			{t: strategy.Tretry},
+			{t: strategy.Tnoupdate},
+			{t: strategy.Tremove},
+		} {
+			t.Run(tc.t.String(), func(t *testing.T) {
+				assert.Equal(t, tc.want, tc.t.RequiresNetwork())
+			})
+		}
+	})
+
+	t.Run("UsesBranch", func(t *testing.T) {
+		for _, tc := range []struct {
+			t    strategy.Type
+			want bool
+		}{
+			{t: strategy.Tunknown},
+			{t: strategy.TinitFromEmpty, want: true},
+			{t: strategy.TinitFromEmptyToLock, want: true},
+			{t: strategy.TinitFromEmptyToLatest, want: true},
+			{t: strategy.TcreateLock, want: true},
+			{t: strategy.TcreateLatestLock, want: true},
+			{t: strategy.TupdateToLock, want: true},
+			{t: strategy.TupdateToLatestWithLock, want: true},
+			{t: strategy.Tnoop},
+			{t: strategy.Tretry},
+			{t: strategy.Tnoupdate},
+			{t: strategy.Tremove},
+		} {
+			t.Run(tc.t.String(), func(t *testing.T) {
+				assert.Equal(t, tc.want, tc.t.UsesBranch())
+			})
+		}
+	})
+}
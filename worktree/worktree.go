@@ -0,0 +1,141 @@
+package worktree
+
+import (
+	"berquerant/install-via-git-go/errorx"
+	"berquerant/install-via-git-go/filepathx"
+	"berquerant/install-via-git-go/git"
+	"berquerant/install-via-git-go/logx"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+var (
+	ErrWorktree = errors.New("Worktree")
+)
+
+// Stage manages the worktrees of the commits and the symlink to the installed one.
+//
+// The layout is:
+//
+//	LINK.d/.repo  the repository
+//	LINK.d/HASH   the worktree of HASH
+//	LINK          the symlink to LINK.d/HASH
+type Stage struct {
+	link filepathx.Path
+	cli  git.CLI
+}
+
+// NewStage returns the Stage of link, cli executes git in RepoDir(link).
+func NewStage(link filepathx.Path, cli git.CLI) *Stage {
+	return &Stage{
+		link: link,
+		cli:  cli,
+	}
+}
+
+// Dir returns the directory of the worktrees of link.
+func Dir(link filepathx.Path) filepathx.DirPath {
+	return filepathx.Path(link.String() + ".d").DirPath()
+}
+
+// RepoDir returns the repository of link.
+func RepoDir(link filepathx.Path) filepathx.DirPath {
+	return Dir(link).Join(".repo").DirPath()
+}
+
+func (s *Stage) Link() filepathx.Path {
+	return s.link
+}
+
+// Path returns the worktree of hash.
+func (s *Stage) Path(hash string) filepathx.DirPath {
+	return Dir(s.link).Join(hash).DirPath()
+}
+
+// Current returns the hash the link points to.
+func (s *Stage) Current() (string, bool) {
+	target, err := os.Readlink(s.link.String())
+	if err != nil {
+		return "", false
+	}
+	return filepath.Base(target), true
+}
+
+// List returns the hashes of the worktrees.
+func (s *Stage) List() ([]string, error) {
+	dir := Dir(s.link)
+	if !dir.Exist() {
+		return nil, nil
+	}
+	entries, err := os.ReadDir(dir.String())
+	if err != nil {
+		return nil, errorx.Errorf(errors.Join(ErrWorktree, err), "list %s", dir)
+	}
+	var hashes []string
+	for _, entry := range entries {
+		if !entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		hashes = append(hashes, entry.Name())
+	}
+	slices.Sort(hashes)
+	return hashes, nil
+}
+
+// Add checkouts hash into its worktree, returns the existing one if any.
+func (s *Stage) Add(ctx context.Context, hash string) (filepathx.DirPath, error) {
+	path := s.Path(hash)
+	if path.Exist() {
+		logx.Info("reuse worktree", logx.S("path", path.String()))
+		return path, nil
+	}
+	logx.Info("add worktree", logx.S("path", path.String()))
+	if _, err := s.cli.Execute(ctx, "worktree", "add", "--detach", path.String(), hash); err != nil {
+		return filepathx.DirPath{}, errorx.Errorf(errors.Join(ErrWorktree, err), "add %s", hash)
+	}
+	return path, nil
+}
+
+// Remove removes the worktree of hash, except the one the link points to.
+func (s *Stage) Remove(ctx context.Context, hash string) error {
+	if current, ok := s.Current(); ok && current == hash {
+		return errorx.Errorf(ErrWorktree, "remove %s: linked", hash)
+	}
+	path := s.Path(hash)
+	logx.Info("remove worktree", logx.S("path", path.String()))
+	if _, err := s.cli.Execute(ctx, "worktree", "remove", "--force", path.String()); err != nil {
+		logx.Debug("git worktree remove", logx.S("path", path.String()), logx.Err(err))
+		if err := path.Remove(); err != nil {
+			return errorx.Errorf(errors.Join(ErrWorktree, err), "remove %s", hash)
+		}
+		_, _ = s.cli.Execute(ctx, "worktree", "prune")
+	}
+	return nil
+}
+
+// Swap points the link to the worktree of hash atomically.
+func (s *Stage) Swap(hash string) error {
+	if info, err := os.Lstat(s.link.String()); err == nil && info.Mode()&os.ModeSymlink == 0 {
+		return errorx.Errorf(ErrWorktree, "swap: %s is not a symlink, remove it to use worktree", s.link)
+	}
+	if !s.Path(hash).Exist() {
+		return errorx.Errorf(ErrWorktree, "swap: no worktree of %s", hash)
+	}
+	// relative to keep workDir relocatable
+	target := filepath.Join(Dir(s.link).Tail(), hash)
+	tmp := s.link.String() + ".tmp"
+	_ = os.Remove(tmp)
+	if err := os.Symlink(target, tmp); err != nil {
+		return errorx.Errorf(errors.Join(ErrWorktree, err), "swap to %s", hash)
+	}
+	if err := os.Rename(tmp, s.link.String()); err != nil {
+		_ = os.Remove(tmp)
+		return errorx.Errorf(errors.Join(ErrWorktree, err), "swap to %s", hash)
+	}
+	logx.Info("swap worktree", logx.S("link", s.link.String()), logx.S("target", target))
+	return nil
+}
@@ -0,0 +1,97 @@
+package worktree_test
+
+import (
+	"berquerant/install-via-git-go/execx"
+	"berquerant/install-via-git-go/filepathx"
+	"berquerant/install-via-git-go/git"
+	"berquerant/install-via-git-go/worktree"
+	"context"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStage(t *testing.T) {
+	base, err := filepathx.NewPath(t.TempDir())
+	if !assert.Nil(t, err) {
+		return
+	}
+	env := execx.EnvFromSlice([]string{
+		"GIT_AUTHOR_NAME=ivg",
+		"GIT_AUTHOR_EMAIL=ivg@example.com",
+		"GIT_COMMITTER_NAME=ivg",
+		"GIT_COMMITTER_EMAIL=ivg@example.com",
+	})
+	link := base.Join("repo")
+	repoDir := worktree.RepoDir(link)
+	if !assert.Nil(t, repoDir.Ensure()) {
+		return
+	}
+	r, err := execx.NewExecutorFromStrings([]string{
+		"git init -q",
+		"git commit -q --allow-empty -m one",
+		"git rev-parse HEAD",
+		"git commit -q --allow-empty -m two",
+		"git rev-parse HEAD",
+	}, "bash").Execute(context.TODO(), execx.WithDir(repoDir), execx.WithEnv(env))
+	if !assert.Nil(t, err) {
+		return
+	}
+	hashes := strings.Fields(r.Stdout)
+	if !assert.Len(t, hashes, 2) {
+		return
+	}
+	one, two := hashes[0], hashes[1]
+
+	stage := worktree.NewStage(link, git.NewCLI(repoDir, env, "git"))
+
+	_, ok := stage.Current()
+	assert.False(t, ok)
+
+	for _, hash := range hashes {
+		path, err := stage.Add(context.TODO(), hash)
+		if !assert.Nil(t, err) {
+			return
+		}
+		assert.Equal(t, stage.Path(hash), path)
+		assert.True(t, path.Exist())
+	}
+	got, err := stage.List()
+	assert.Nil(t, err)
+	assert.ElementsMatch(t, hashes, got)
+
+	t.Run("swap", func(t *testing.T) {
+		assert.Nil(t, stage.Swap(one))
+		current, ok := stage.Current()
+		assert.True(t, ok)
+		assert.Equal(t, one, current)
+
+		assert.Nil(t, stage.Swap(two))
+		current, ok = stage.Current()
+		assert.True(t, ok)
+		assert.Equal(t, two, current)
+		assert.True(t, link.DirPath().Exist())
+	})
+
+	t.Run("swap missing", func(t *testing.T) {
+		assert.ErrorIs(t, stage.Swap("missing"), worktree.ErrWorktree)
+	})
+
+	t.Run("remove", func(t *testing.T) {
+		assert.ErrorIs(t, stage.Remove(context.TODO(), two), worktree.ErrWorktree)
+		assert.Nil(t, stage.Remove(context.TODO(), one))
+		assert.False(t, stage.Path(one).Exist())
+		got, err := stage.List()
+		assert.Nil(t, err)
+		assert.Equal(t, []string{two}, got)
+	})
+
+	t.Run("not symlink", func(t *testing.T) {
+		dir := base.Join("plain")
+		assert.Nil(t, os.Mkdir(dir.String(), 0750))
+		s := worktree.NewStage(dir, git.NewCLI(repoDir, env, "git"))
+		assert.ErrorIs(t, s.Swap(two), worktree.ErrWorktree)
+	})
+}